@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// checkPortable prints portability problems as warnings, or fails with
+// them when portable is set so nothing is created.
+func checkPortable(w io.Writer, tree *treeforge.Tree, portable bool) error {
+	issues := tree.CheckPortable()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	label := "Warning"
+	if portable {
+		label = "Error"
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s\n", label, issue)
+	}
+
+	if portable {
+		return fmt.Errorf("%d portability problem(s); nothing was created", len(issues))
+	}
+	return nil
+}
+
+// checkDuplicates merges repeated entries, warning about repeated files,
+// and fails on file/directory conflicts before anything is created.
+func checkDuplicates(w io.Writer, tree *treeforge.Tree) error {
+	warnings, conflicts := tree.Dedupe()
+	for _, issue := range warnings {
+		fmt.Fprintf(w, "Warning: %s\n", issue)
+	}
+	for _, issue := range conflicts {
+		fmt.Fprintf(w, "Error: %s\n", issue)
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d conflicting entry(s); nothing was created", len(conflicts))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestCheckPortable(t *testing.T) {
+	tree := &treeforge.Tree{
+		Root: "myapp",
+		Entries: []treeforge.Entry{
+			{Path: "aux.go", Kind: treeforge.KindFile},
+			{Path: "main.go", Kind: treeforge.KindFile},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := checkPortable(&buf, tree, false); err != nil {
+		t.Errorf("checkPortable() unexpected error without --portable: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Warning: aux.go:") {
+		t.Errorf("checkPortable() output = %q, want a warning for aux.go", buf.String())
+	}
+
+	buf.Reset()
+	if err := checkPortable(&buf, tree, true); err == nil {
+		t.Errorf("checkPortable() expected error with --portable")
+	}
+	if !strings.HasPrefix(buf.String(), "Error: aux.go:") {
+		t.Errorf("checkPortable() output = %q, want an error for aux.go", buf.String())
+	}
+
+	clean := &treeforge.Tree{Root: "myapp", Entries: tree.Entries[1:]}
+	buf.Reset()
+	if err := checkPortable(&buf, clean, true); err != nil || buf.Len() != 0 {
+		t.Errorf("checkPortable() on clean tree = %v, %q", err, buf.String())
+	}
+}
+
+func TestCheckDuplicates(t *testing.T) {
+	tree := &treeforge.Tree{
+		Root: "myapp",
+		Entries: []treeforge.Entry{
+			{Path: "main.go", Kind: treeforge.KindFile, Line: 2},
+			{Path: "main.go", Kind: treeforge.KindFile, Line: 3},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := checkDuplicates(&buf, tree); err != nil {
+		t.Errorf("checkDuplicates() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "Warning: line 3: main.go:") || len(tree.Entries) != 1 {
+		t.Errorf("checkDuplicates() output = %q, entries = %v", buf.String(), tree.Entries)
+	}
+
+	conflict := &treeforge.Tree{
+		Root: "myapp",
+		Entries: []treeforge.Entry{
+			{Path: "config", Kind: treeforge.KindFile, Line: 2},
+			{Path: "config", Kind: treeforge.KindDir, Line: 3},
+		},
+	}
+	buf.Reset()
+	if err := checkDuplicates(&buf, conflict); err == nil {
+		t.Errorf("checkDuplicates() expected error for file/directory conflict")
+	}
+	if !strings.HasPrefix(buf.String(), "Error: line 3: config:") {
+		t.Errorf("checkDuplicates() output = %q, want an error for config", buf.String())
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// checkpoint records the targets of completed steps, one per line, so an
+// apply that was interrupted or failed can pick up where it stopped.
+type checkpoint struct {
+	path string
+	f    *os.File
+	err  error
+}
+
+// checkpointKey identifies a run by its input and every option that
+// changes what gets created. Verbosity and the number of jobs do not.
+func checkpointKey(lines []string, cfg *Config, noIgnore bool) string {
+	h := sha256.New()
+	for _, line := range lines {
+		fmt.Fprintf(h, "%s\n", line)
+	}
+	parent, err := filepath.Abs(cfg.Parent)
+	if err != nil {
+		parent = cfg.Parent
+	}
+	fmt.Fprintf(h, "\x00parent=%s\n", parent)
+	for _, key := range configKeys {
+		if key == "parent" || key == "verbose" || key == "jobs" {
+			continue
+		}
+		fmt.Fprintf(h, "%s=%s\n", key, configFields[key].get(cfg))
+	}
+	fmt.Fprintf(h, "no-ignore=%t\n", noIgnore)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// checkpointPath returns where the checkpoint for key is kept.
+// TREEFORGE_CACHE overrides the default directory.
+func checkpointPath(key string) (string, error) {
+	dir := os.Getenv("TREEFORGE_CACHE")
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(cache, "treeforge")
+	}
+	return filepath.Join(dir, "checkpoints", key), nil
+}
+
+// startCheckpoint opens the checkpoint for key and hooks it into plan.
+// With resume, the steps an earlier run completed are dropped from plan
+// first; without it, any earlier checkpoint is discarded.
+func startCheckpoint(plan *treeforge.Plan, key string, resume bool) (*checkpoint, error) {
+	path, err := checkpointPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		done, err := readCheckpoint(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(done) == 0 {
+			fmt.Println("No checkpoint for this input and options; starting from the beginning.")
+		} else {
+			fmt.Printf("Resuming: %d entries already done\n", plan.Resume(done))
+		}
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{path: path, f: f}
+	plan.OnResult = c.record
+	return c, nil
+}
+
+// readCheckpoint returns the targets recorded at path, or none if there
+// is no checkpoint.
+func readCheckpoint(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	done := map[string]bool{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, math.MaxInt32)
+	for sc.Scan() {
+		done[sc.Text()] = true
+	}
+	return done, sc.Err()
+}
+
+// record appends a completed step. The first write error is kept and
+// reported by finish; the apply itself carries on.
+func (c *checkpoint) record(res treeforge.Result) {
+	if c.err == nil {
+		_, c.err = fmt.Fprintln(c.f, res.Step.Target)
+	}
+}
+
+// finish closes the checkpoint, removing it once it is no longer needed
+// because the run completed or was rolled back.
+func (c *checkpoint) finish(keep bool) error {
+	err := errors.Join(c.err, c.f.Close())
+	if !keep {
+		if rerr := os.Remove(c.path); rerr != nil && !errors.Is(rerr, fs.ErrNotExist) {
+			err = errors.Join(err, rerr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("checkpoint %s: %w", c.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestCheckpointKey(t *testing.T) {
+	lines := []string{"myapp/", "└─ main.go"}
+	base := checkpointKey(lines, defaultConfig(), false)
+
+	cfg := defaultConfig()
+	cfg.Verbose, cfg.Jobs = true, 8
+	if got := checkpointKey(lines, cfg, false); got != base {
+		t.Errorf("checkpointKey() changed with --verbose and --jobs")
+	}
+
+	changed := map[string]string{
+		"input":     checkpointKey([]string{"myapp/", "└─ other.go"}, defaultConfig(), false),
+		"no-ignore": checkpointKey(lines, defaultConfig(), true),
+	}
+	cfg = defaultConfig()
+	cfg.Force = true
+	changed["force"] = checkpointKey(lines, cfg, false)
+	cfg = defaultConfig()
+	cfg.Parent = "elsewhere"
+	changed["parent"] = checkpointKey(lines, cfg, false)
+	for what, key := range changed {
+		if key == base {
+			t.Errorf("checkpointKey() did not change with %s", what)
+		}
+	}
+}
+
+func TestApplyCheckpointed(t *testing.T) {
+	t.Setenv("TREEFORGE_CACHE", t.TempDir())
+	tmpDir := t.TempDir()
+	// A file where the tree wants a directory makes the apply fail part-way.
+	if err := os.WriteFile(filepath.Join(tmpDir, "blocker"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create blocker: %v", err)
+	}
+	tree := &treeforge.Tree{
+		Root: ".",
+		Entries: []treeforge.Entry{
+			{Path: "main.go", Kind: treeforge.KindFile, Content: []byte("package main\n")},
+			{Path: "blocker/file.txt", Kind: treeforge.KindFile},
+		},
+	}
+	newPlan := func() *treeforge.Plan {
+		plan := treeforge.NewPlan(tree, tmpDir)
+		plan.Force = true
+		return plan
+	}
+
+	flags := &cliFlags{apply: true}
+	if _, err := applyCheckpointed(context.Background(), newPlan(), flags, false, "k"); err == nil {
+		t.Fatal("applyCheckpointed() expected error")
+	}
+	path, _ := checkpointPath("k")
+	done, err := readCheckpoint(path)
+	if err != nil || !done[filepath.Join(tmpDir, "main.go")] || len(done) != 1 {
+		t.Fatalf("checkpoint after failure = %v, %v; want only main.go", done, err)
+	}
+
+	// The user fills in main.go, clears the blocker and resumes with
+	// --force: main.go is not clobbered.
+	mainGo := filepath.Join(tmpDir, "main.go")
+	if err := os.WriteFile(mainGo, []byte("edited"), 0644); err != nil {
+		t.Fatalf("Failed to edit main.go: %v", err)
+	}
+	if err := os.Remove(filepath.Join(tmpDir, "blocker")); err != nil {
+		t.Fatalf("Failed to remove blocker: %v", err)
+	}
+	flags.resume = true
+	if _, err := applyCheckpointed(context.Background(), newPlan(), flags, false, "k"); err != nil {
+		t.Fatalf("applyCheckpointed() resume unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(mainGo); string(data) != "edited" {
+		t.Errorf("resume overwrote main.go: %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "blocker", "file.txt")); err != nil {
+		t.Errorf("resume did not create blocker/file.txt: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint left behind after a successful run")
+	}
+}
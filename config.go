@@ -0,0 +1,449 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	projectConfigName = ".treeforge.yaml"
+	sourceDefault     = "default"
+	sourceFlag        = "flag"
+)
+
+// Config holds the settings that can be given in config files as well as
+// on the command line. Precedence, lowest to highest: built-in defaults,
+// the user config, the project config, then explicitly set flags.
+type Config struct {
+	Parent   string
+	RootName string
+	Force    bool
+	Verbose  bool
+
+	// Git initializes a repository in the created root, with .gitkeep
+	// files in empty directories; GitCommit also commits the scaffold.
+	Git        bool
+	GitCommit  bool
+	GitMessage string
+
+	// Exclude holds gitignore-style patterns for entries to leave out.
+	// Patterns given with --exclude are added to those from config files.
+	Exclude []string
+
+	// Portable turns cross-platform name warnings into errors.
+	Portable bool
+
+	// Normalize lists the Unicode clean-ups applied to names: "all",
+	// "none", or a comma-separated subset of nfc, spaces, invisible,
+	// width and quotes.
+	Normalize string
+
+	// Strict rejects ambiguous diagrams instead of guessing.
+	Strict bool
+
+	// Jobs is the number of entries created at once with --apply.
+	Jobs int
+
+	// OnConflict says what to do with files that already exist: skip,
+	// overwrite, backup, rename, merge or ask. Merge writes diff3-style
+	// markers with an empty base section. Force means overwrite.
+	OnConflict string
+
+	// Hooks are commands run after an apply; see Hook. A project config
+	// only adds hooks when it is in the directory treeforge runs in;
+	// IgnoredHooks names one found further up whose hooks were left out.
+	Hooks        []Hook
+	IgnoredHooks string
+
+	// ContentDir is a directory whose files provide the content of file
+	// entries at the same relative paths.
+	ContentDir string
+
+	// Vars are the variables content templates are rendered with and
+	// "# if:" conditions are evaluated against. Values given with --var
+	// are added to those from config files.
+	Vars map[string]string
+
+	// Source records where each key's effective value came from.
+	Source map[string]string
+}
+
+type configField struct {
+	get func(c *Config) string
+	set func(c *Config, v any) error
+}
+
+// configKeys lists the config keys in display order.
+var configKeys = []string{"parent", "root-name", "force", "verbose", "git", "git-commit", "git-message", "exclude", "portable", "normalize", "strict", "jobs", "on-conflict", "hooks", "content-dir", "vars"}
+
+var configFields = map[string]configField{
+	"parent": {
+		get: func(c *Config) string { return c.Parent },
+		set: func(c *Config, v any) (err error) { c.Parent, err = configString(v); return },
+	},
+	"root-name": {
+		get: func(c *Config) string { return c.RootName },
+		set: func(c *Config, v any) (err error) { c.RootName, err = configString(v); return },
+	},
+	"force": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Force) },
+		set: func(c *Config, v any) (err error) { c.Force, err = configBool(v); return },
+	},
+	"verbose": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Verbose) },
+		set: func(c *Config, v any) (err error) { c.Verbose, err = configBool(v); return },
+	},
+	"git": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Git) },
+		set: func(c *Config, v any) (err error) { c.Git, err = configBool(v); return },
+	},
+	"git-commit": {
+		get: func(c *Config) string { return strconv.FormatBool(c.GitCommit) },
+		set: func(c *Config, v any) (err error) { c.GitCommit, err = configBool(v); return },
+	},
+	"git-message": {
+		get: func(c *Config) string { return c.GitMessage },
+		set: func(c *Config, v any) (err error) { c.GitMessage, err = configString(v); return },
+	},
+	"exclude": {
+		get: func(c *Config) string { return "[" + strings.Join(c.Exclude, ", ") + "]" },
+		set: func(c *Config, v any) (err error) { c.Exclude, err = configStrings(v); return },
+	},
+	"portable": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Portable) },
+		set: func(c *Config, v any) (err error) { c.Portable, err = configBool(v); return },
+	},
+	"normalize": {
+		get: func(c *Config) string { return c.Normalize },
+		set: func(c *Config, v any) (err error) { c.Normalize, err = configString(v); return },
+	},
+	"strict": {
+		get: func(c *Config) string { return strconv.FormatBool(c.Strict) },
+		set: func(c *Config, v any) (err error) { c.Strict, err = configBool(v); return },
+	},
+	"jobs": {
+		get: func(c *Config) string { return strconv.Itoa(c.Jobs) },
+		set: func(c *Config, v any) (err error) { c.Jobs, err = configInt(v); return },
+	},
+	"on-conflict": {
+		get: func(c *Config) string { return c.OnConflict },
+		set: func(c *Config, v any) (err error) { c.OnConflict, err = configString(v); return },
+	},
+	"hooks": {
+		get: func(c *Config) string {
+			hooks := make([]string, len(c.Hooks))
+			for i, h := range c.Hooks {
+				hooks[i] = h.String()
+			}
+			return "[" + strings.Join(hooks, ", ") + "]"
+		},
+		set: func(c *Config, v any) (err error) { c.Hooks, err = configHooks(v); return },
+	},
+	"content-dir": {
+		get: func(c *Config) string { return c.ContentDir },
+		set: func(c *Config, v any) (err error) { c.ContentDir, err = configString(v); return },
+	},
+	"vars": {
+		get: func(c *Config) string { return (*varMap)(&c.Vars).String() },
+		set: func(c *Config, v any) (err error) { c.Vars, err = configVars(v); return },
+	},
+}
+
+// flagKeys maps command-line flag names to config keys where they differ.
+var flagKeys = map[string]string{"v": "verbose", "var": "vars"}
+
+func defaultConfig() *Config {
+	c := &Config{Parent: ".", GitMessage: defaultGitMessage, Normalize: "all", Jobs: 1, OnConflict: "skip", Source: map[string]string{}}
+	for _, key := range configKeys {
+		c.Source[key] = sourceDefault
+	}
+	return c
+}
+
+// loadConfig builds the effective configuration for a run started in dir.
+func loadConfig(dir string) (*Config, error) {
+	c := defaultConfig()
+
+	if path, ok := userConfigPath(); ok {
+		if err := c.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if path, ok := findProjectConfig(dir); ok {
+		hooks, source := c.Hooks, c.Source["hooks"]
+		if err := c.mergeFile(path); err != nil {
+			return nil, err
+		}
+		// Hooks run commands, so a config found in a parent directory,
+		// which need not belong to this project, cannot add them.
+		if abs, err := filepath.Abs(dir); c.Source["hooks"] == path && (err != nil || filepath.Dir(path) != abs) {
+			c.Hooks, c.Source["hooks"], c.IgnoredHooks = hooks, source, path
+		}
+	}
+
+	return c, nil
+}
+
+// userConfigPath returns the per-user config file, if one exists.
+// TREEFORGE_CONFIG overrides the default location.
+func userConfigPath() (string, bool) {
+	path := os.Getenv("TREEFORGE_CONFIG")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(dir, "treeforge", "config.yaml")
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// findProjectConfig looks for .treeforge.yaml in dir and each of its parents.
+func findProjectConfig(dir string) (string, bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config %s: %w", path, err)
+	}
+	values, err := parseYAML(data)
+	if err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	if err := c.merge(values, path); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	// A relative parent or content-dir in a config file is relative to
+	// that file, not to wherever treeforge happens to be run from.
+	if c.Source["parent"] == path {
+		c.Parent = resolveConfigPath(c.Parent, filepath.Dir(path))
+	}
+	if c.Source["content-dir"] == path && c.ContentDir != "" {
+		c.ContentDir = resolveConfigPath(c.ContentDir, filepath.Dir(path))
+	}
+	return nil
+}
+
+// resolveConfigPath expands a leading "~/" and anchors relative paths at dir.
+func resolveConfigPath(path, dir string) string {
+	if home, err := os.UserHomeDir(); err == nil && (path == "~" || strings.HasPrefix(path, "~/")) {
+		return filepath.Join(home, path[1:])
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func (c *Config) merge(values map[string]any, source string) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := c.set(key, values[key], source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) set(key string, value any, source string) error {
+	field, ok := configFields[key]
+	if !ok {
+		return fmt.Errorf("unknown key %q", key)
+	}
+	if err := field.set(c, value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	c.Source[key] = source
+	return nil
+}
+
+// bindFlags registers the config-backed flags on fs, using the current
+// config values as their defaults.
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Parent, "parent", c.Parent, "Parent directory to create structure in")
+	fs.StringVar(&c.RootName, "root-name", c.RootName, "Override root directory name (from first line if empty)")
+	fs.BoolVar(&c.Force, "force", c.Force, "Overwrite existing files (directories are not deleted)")
+	fs.BoolVar(&c.Verbose, "v", c.Verbose, "Verbose output")
+	fs.BoolVar(&c.Git, "git", c.Git, "Initialize a git repository in the root and add .gitkeep to empty directories")
+	fs.BoolVar(&c.GitCommit, "git-commit", c.GitCommit, "With --git, commit the created structure")
+	fs.StringVar(&c.GitMessage, "git-message", c.GitMessage, "Message for the --git-commit commit")
+	fs.Var((*stringList)(&c.Exclude), "exclude", "Leave out entries matching a gitignore-style pattern (repeatable)")
+	fs.BoolVar(&c.Portable, "portable", c.Portable, "Treat names that break on Windows or macOS as errors instead of warnings")
+	fs.StringVar(&c.Normalize, "normalize", c.Normalize, "Unicode clean-up of names: all, none, or a list of nfc,spaces,invisible,width,quotes")
+	fs.BoolVar(&c.Strict, "strict", c.Strict, "Reject inconsistent indentation, level jumps, unknown glyphs and names that lose leading characters")
+	fs.IntVar(&c.Jobs, "jobs", c.Jobs, "Number of entries to create in parallel with --apply")
+	fs.StringVar(&c.OnConflict, "on-conflict", c.OnConflict, "What to do with existing files: skip, overwrite, backup, rename, merge (diff3-style markers: <<<<<<< existing, ||||||| base (empty), =======, >>>>>>> tree) or ask")
+	fs.StringVar(&c.ContentDir, "content-dir", c.ContentDir, "Directory whose files fill in the content of file entries at the same paths")
+	fs.Var((*varMap)(&c.Vars), "var", "Set a variable for content templates and # if: conditions as NAME=VALUE (repeatable)")
+}
+
+// markFlags records explicitly set flags as the source of their keys.
+func (c *Config) markFlags(fs *flag.FlagSet) {
+	fs.Visit(func(f *flag.Flag) {
+		key := f.Name
+		if k, ok := flagKeys[key]; ok {
+			key = k
+		}
+		if _, ok := configFields[key]; ok {
+			c.Source[key] = sourceFlag
+		}
+	})
+}
+
+func printConfig(w io.Writer, c *Config) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, key := range configKeys {
+		fmt.Fprintf(tw, "%s:\t%s\t# %s\n", key, configFields[key].get(c), c.Source[key])
+	}
+	tw.Flush()
+}
+
+func configString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.New("expected a string")
+	}
+	return s, nil
+}
+
+func configInt(v any) (int, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, errors.New("expected a number")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("expected a number, got %q", s)
+	}
+	return n, nil
+}
+
+// configStrings accepts a list of strings or a single string.
+func configStrings(v any) ([]string, error) {
+	if s, ok := v.(string); ok {
+		return []string{s}, nil
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, errors.New("expected a list of strings")
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, errors.New("expected a list of strings")
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// stringList is a repeatable flag that appends to a slice.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// configVars reads a mapping of variable names to scalar values.
+func configVars(v any) (map[string]string, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping of names to values")
+	}
+	vars := make(map[string]string, len(m))
+	for name, value := range m {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a single value", name)
+		}
+		vars[name] = s
+	}
+	return vars, nil
+}
+
+// varMap is a repeatable NAME=VALUE flag that adds to a map.
+type varMap map[string]string
+
+func (m *varMap) String() string {
+	if m == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*m))
+	for name := range *m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = name + "=" + (*m)[name]
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func (m *varMap) Set(v string) error {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected NAME=VALUE, got %q", v)
+	}
+	if *m == nil {
+		*m = map[string]string{}
+	}
+	(*m)[strings.TrimSpace(name)] = value
+	return nil
+}
+
+func configBool(v any) (bool, error) {
+	s, ok := v.(string)
+	if !ok {
+		return false, errors.New("expected true or false")
+	}
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("expected true or false, got %q", s)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create config dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+}
+
+func TestFindProjectConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	nested := filepath.Join(tmpDir, "a", "b", "c")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatalf("Failed to create dirs: %v", err)
+	}
+
+	if path, ok := findProjectConfig(nested); ok && strings.HasPrefix(path, tmpDir) {
+		t.Errorf("findProjectConfig() found %s before any config was written", path)
+	}
+
+	want := filepath.Join(tmpDir, "a", projectConfigName)
+	writeConfigFile(t, want, "force: true\n")
+
+	path, ok := findProjectConfig(nested)
+	if !ok || path != want {
+		t.Errorf("findProjectConfig() = %q, %v; want %q", path, ok, want)
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tmpDir := t.TempDir()
+	userConfig := filepath.Join(tmpDir, "user.yaml")
+	writeConfigFile(t, userConfig, "parent: /from/user\nforce: true\nverbose: yes\n")
+	t.Setenv("TREEFORGE_CONFIG", userConfig)
+
+	project := filepath.Join(tmpDir, "project")
+	projectConfig := filepath.Join(project, projectConfigName)
+	writeConfigFile(t, projectConfig, "parent: build\n")
+
+	cfg, err := loadConfig(project)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.bindFlags(fs)
+	if err := fs.Parse([]string{"--force=false"}); err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	cfg.markFlags(fs)
+
+	checks := []struct {
+		key, value, source string
+	}{
+		{"parent", filepath.Join(project, "build"), projectConfig},
+		{"force", "false", sourceFlag},
+		{"verbose", "true", userConfig},
+		{"root-name", "", sourceDefault},
+	}
+	for _, c := range checks {
+		if got := configFields[c.key].get(cfg); got != c.value {
+			t.Errorf("%s = %q, want %q", c.key, got, c.value)
+		}
+		if got := cfg.Source[c.key]; got != c.source {
+			t.Errorf("%s source = %q, want %q", c.key, got, c.source)
+		}
+	}
+}
+
+func TestConfigExclude(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+	writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), "exclude:\n  - node_modules/\n  - \"*.log\"\n")
+
+	cfg, err := loadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.bindFlags(fs)
+	if err := fs.Parse([]string{"--exclude", "dist/"}); err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	want := []string{"node_modules/", "*.log", "dist/"}
+	if !reflect.DeepEqual(cfg.Exclude, want) {
+		t.Errorf("Exclude = %v, want %v", cfg.Exclude, want)
+	}
+}
+
+func TestConfigJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+
+	cfg, err := loadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	if cfg.Jobs != 1 {
+		t.Errorf("default Jobs = %d, want 1", cfg.Jobs)
+	}
+
+	writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), "jobs: 8\n")
+	if cfg, err = loadConfig(tmpDir); err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	if cfg.Jobs != 8 {
+		t.Errorf("Jobs = %d, want 8", cfg.Jobs)
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "~/projects", expected: filepath.Join(home, "projects")},
+		{path: "~", expected: home},
+		{path: "/abs/path", expected: "/abs/path"},
+		{path: "build", expected: filepath.Join("/config/dir", "build")},
+		{path: ".", expected: "/config/dir"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := resolveConfigPath(tt.path, "/config/dir"); got != tt.expected {
+				t.Errorf("resolveConfigPath(%q) = %q, want %q", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unknown key", content: "paren: /tmp\n"},
+		{name: "invalid bool", content: "force: maybe\n"},
+		{name: "list for string", content: "parent: [a, b]\n"},
+		{name: "mapping for list", content: "exclude:\n  a: b\n"},
+		{name: "invalid yaml", content: "parent\n"},
+		{name: "invalid number", content: "jobs: many\n"},
+		{name: "list for vars", content: "vars: [a, b]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+			writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), tt.content)
+
+			if _, err := loadConfig(tmpDir); err == nil {
+				t.Errorf("loadConfig() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	cfg := defaultConfig()
+	if err := cfg.set("root-name", "myapp", "test.yaml"); err != nil {
+		t.Fatalf("set() unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	printConfig(&buf, cfg)
+
+	out := buf.String()
+	for _, key := range configKeys {
+		if !strings.Contains(out, key+":") {
+			t.Errorf("printConfig() output missing %q:\n%s", key, out)
+		}
+	}
+	if !strings.Contains(out, "myapp") || !strings.Contains(out, "# test.yaml") {
+		t.Errorf("printConfig() output missing root-name value or source:\n%s", out)
+	}
+}
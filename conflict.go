@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// setConflicts applies the on-conflict setting to plan. With "ask",
+// existing files are settled one by one through prompt, which is nil
+// when nothing is applied.
+func setConflicts(plan *treeforge.Plan, cfg *Config, prompt *conflictPrompt) error {
+	c, err := treeforge.ParseConflict(cfg.OnConflict)
+	if err != nil {
+		return err
+	}
+	plan.OnConflict = c
+	if c == treeforge.ConflictAsk && prompt != nil {
+		// A capital answer lasts for one plan only.
+		prompt.always = treeforge.ConflictAsk
+		plan.Ask = prompt.ask
+	}
+	return nil
+}
+
+// checkConflictFlags rejects policies the chosen output cannot honour.
+func checkConflictFlags(flags *cliFlags, cfg *Config) error {
+	switch {
+	case flags.emit != "" && cfg.OnConflict != "skip" && cfg.OnConflict != "overwrite":
+		return fmt.Errorf("--emit supports --on-conflict skip or overwrite, not %s", cfg.OnConflict)
+	case flags.rollback && cfg.OnConflict == "backup":
+		return errors.New("--rollback cannot be combined with --on-conflict backup: rolling back would delete the backups")
+	}
+	return nil
+}
+
+// conflictPrompt asks on the terminal what to do with each existing file.
+// An answer in capitals applies to every file after it as well.
+type conflictPrompt struct {
+	ctx    context.Context
+	tty    *os.File
+	lines  chan string
+	always treeforge.Conflict
+}
+
+// newConflictPrompt returns the prompt for --apply, or nil when nothing
+// is applied. The terminal is opened on the first question.
+func newConflictPrompt(ctx context.Context, apply bool) *conflictPrompt {
+	if !apply {
+		return nil
+	}
+	return &conflictPrompt{ctx: ctx, always: treeforge.ConflictAsk}
+}
+
+var conflictAnswers = map[string]treeforge.Conflict{
+	"o": treeforge.ConflictOverwrite,
+	"s": treeforge.ConflictSkip,
+	"b": treeforge.ConflictBackup,
+	"r": treeforge.ConflictRename,
+	"m": treeforge.ConflictMerge,
+}
+
+func (p *conflictPrompt) ask(step treeforge.Step, existing []byte) (treeforge.Conflict, error) {
+	if p.always != treeforge.ConflictAsk {
+		return p.always, nil
+	}
+	if err := p.open(); err != nil {
+		return 0, err
+	}
+
+	fmt.Fprintf(p.tty, "\n%s already exists:\n", step.Target)
+	for _, line := range diffLines(existing, step.Entry.Content, 20) {
+		fmt.Fprintf(p.tty, "  %s\n", line)
+	}
+	for {
+		fmt.Fprint(p.tty, "[o]verwrite, [s]kip, [b]ackup, [r]ename, [m]erge (capital for all remaining)? ")
+		select {
+		case <-p.ctx.Done():
+			fmt.Fprintln(p.tty)
+			return 0, p.ctx.Err()
+		case answer, ok := <-p.lines:
+			if !ok {
+				return 0, errors.New("no answer to the overwrite prompt")
+			}
+			if c, found := conflictAnswers[strings.ToLower(answer)]; found {
+				if answer != strings.ToLower(answer) {
+					p.always = c
+				}
+				return c, nil
+			}
+		}
+	}
+}
+
+// open connects to the terminal on first use. Answers are read in the
+// background so that an interrupt can end a pending prompt.
+func (p *conflictPrompt) open() error {
+	if p.tty != nil {
+		return nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return fmt.Errorf("--on-conflict ask needs a terminal: %w", err)
+	}
+	p.tty, p.lines = tty, make(chan string)
+	go func() {
+		defer close(p.lines)
+		sc := bufio.NewScanner(tty)
+		for sc.Scan() {
+			select {
+			case p.lines <- strings.TrimSpace(sc.Text()):
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// close releases the terminal, which also ends the background reader.
+func (p *conflictPrompt) close() {
+	if p != nil && p.tty != nil {
+		p.tty.Close()
+		p.tty = nil
+	}
+}
+
+// diffLines previews how new differs from old, returning at most limit
+// lines marked "-" for removed and "+" for added, with unchanged runs
+// left out.
+func diffLines(old, new []byte, limit int) []string {
+	if bytes.Equal(old, new) {
+		return []string{"(contents are identical)"}
+	}
+
+	var out []string
+	for _, op := range treeforge.DiffLines(old, new) {
+		if op[0] == ' ' {
+			continue
+		}
+		if len(out) == limit {
+			return append(out, "...")
+		}
+		out = append(out, op)
+	}
+	if len(new) == 0 {
+		out = append(out, "(the tree gives this file no content)")
+	}
+	return out
+}
+
+// summary formats the counts printed at the end of an apply, naming
+// conflict decisions only when there were any.
+func summary(count func(treeforge.Status) int) string {
+	s := fmt.Sprintf("Created: %d, Skipped: %d", count(treeforge.StatusCreated), count(treeforge.StatusSkipped))
+	for _, st := range []treeforge.Status{
+		treeforge.StatusUnchanged, treeforge.StatusFilled, treeforge.StatusDiffers,
+		treeforge.StatusOverwritten, treeforge.StatusBackedUp, treeforge.StatusMerged, treeforge.StatusRenamed,
+	} {
+		if n := count(st); n > 0 {
+			label := st.String()
+			s += fmt.Sprintf(", %s%s: %d", strings.ToUpper(label[:1]), label[1:], n)
+		}
+	}
+	return s
+}
+
+// needsAttention reports whether a result left something for the user to
+// look at: a backup, a file written under another name, a file that
+// differs from the tree, or conflict markers.
+func needsAttention(st treeforge.Status) bool {
+	switch st {
+	case treeforge.StatusBackedUp, treeforge.StatusRenamed, treeforge.StatusDiffers, treeforge.StatusMerged:
+		return true
+	}
+	return false
+}
+
+// printConflictPaths lists the files an apply left for the user to look
+// at, which are easy to miss without -v.
+func printConflictPaths(w io.Writer, results []treeforge.Result) {
+	for _, res := range results {
+		switch res.Status {
+		case treeforge.StatusDiffers:
+			fmt.Fprintf(w, "  differs: %s (kept; see --on-conflict)\n", res.Step.Target)
+		case treeforge.StatusMerged:
+			fmt.Fprintf(w, "  merged: %s (resolve the conflict markers)\n", res.Step.Target)
+		case treeforge.StatusBackedUp:
+			fmt.Fprintf(w, "  backup: %s -> %s\n", res.Step.Target, res.Path)
+		case treeforge.StatusRenamed:
+			fmt.Fprintf(w, "  written as: %s (%s exists)\n", res.Path, res.Step.Target)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		limit    int
+		want     []string
+	}{
+		{name: "identical", old: "a\n", new: "a\n", limit: 10, want: []string{"(contents are identical)"}},
+		{name: "changed line", old: "a\nb\nc\n", new: "a\nB\nc\n", limit: 10, want: []string{"-b", "+B"}},
+		{name: "emptied", old: "a\nb\n", new: "", limit: 10, want: []string{"-a", "-b", "(the tree gives this file no content)"}},
+		{name: "limited", old: "1\n2\n3\n", new: "", limit: 2, want: []string{"-1", "-2", "..."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines([]byte(tt.old), []byte(tt.new), tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	counts := map[treeforge.Status]int{treeforge.StatusCreated: 3, treeforge.StatusBackedUp: 1, treeforge.StatusUnchanged: 2, treeforge.StatusDiffers: 1}
+	got := summary(func(st treeforge.Status) int { return counts[st] })
+	if want := "Created: 3, Skipped: 0, Unchanged: 2, Differs: 1, Backed up: 1"; got != want {
+		t.Errorf("summary() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckConflictFlags(t *testing.T) {
+	tests := []struct {
+		flags  cliFlags
+		policy string
+		want   string
+	}{
+		{flags: cliFlags{emit: "sh"}, policy: "overwrite"},
+		{flags: cliFlags{emit: "sh"}, policy: "ask", want: "--emit supports"},
+		{flags: cliFlags{rollback: true}, policy: "rename"},
+		{flags: cliFlags{rollback: true}, policy: "backup", want: "delete the backups"},
+	}
+
+	for _, tt := range tests {
+		cfg := defaultConfig()
+		cfg.OnConflict = tt.policy
+		err := checkConflictFlags(&tt.flags, cfg)
+		if (err == nil) != (tt.want == "") || (err != nil && !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("checkConflictFlags(%+v, %s) = %v, want %q", tt.flags, tt.policy, err, tt.want)
+		}
+	}
+}
+
+func TestSetConflictsReusesPrompt(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.OnConflict = "ask"
+	if p := newConflictPrompt(context.Background(), false); p != nil {
+		t.Fatalf("newConflictPrompt() without --apply = %v, want nil", p)
+	}
+
+	// A watch session hands the same prompt to every plan; a capital
+	// answer from the last plan must not carry over.
+	prompt := newConflictPrompt(context.Background(), true)
+	prompt.always = treeforge.ConflictOverwrite
+	plan := treeforge.NewPlan(&treeforge.Tree{Root: "app"}, t.TempDir())
+	if err := setConflicts(plan, cfg, prompt); err != nil {
+		t.Fatalf("setConflicts() unexpected error: %v", err)
+	}
+	if plan.Ask == nil || prompt.always != treeforge.ConflictAsk {
+		t.Errorf("setConflicts() did not reset the shared prompt")
+	}
+
+	plan = treeforge.NewPlan(&treeforge.Tree{Root: "app"}, t.TempDir())
+	if err := setConflicts(plan, cfg, nil); err != nil || plan.Ask != nil {
+		t.Errorf("setConflicts() without a prompt: Ask set = %v, err = %v", plan.Ask != nil, err)
+	}
+}
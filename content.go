@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// templateVars turns the configured variables into template data. Values
+// that the config accepts as booleans (true/false, yes/no, on/off) become
+// booleans, so that they can be tested with {{ if }} and "# if:" the same
+// way everywhere. Every other value stays a string; numbers are kept as
+// written for content templates.
+func templateVars(vars map[string]string) map[string]any {
+	data := make(map[string]any, len(vars))
+	for name, value := range vars {
+		if b, err := configBool(value); err == nil {
+			data[name] = b
+		} else {
+			data[name] = value
+		}
+	}
+	return data
+}
+
+// checkContentDir makes sure --content-dir names a directory.
+func checkContentDir(cfg *Config) error {
+	if cfg.ContentDir == "" {
+		return nil
+	}
+	info, err := os.Stat(cfg.ContentDir)
+	if err != nil {
+		return fmt.Errorf("content directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("content directory %s is not a directory", cfg.ContentDir)
+	}
+	return nil
+}
+
+// fillContent reads file contents for tree from --content-dir and then,
+// for entries still empty, from the repository or archive it came from.
+func fillContent(tree *treeforge.Tree, in *input, cfg *Config) error {
+	if err := checkContentDir(cfg); err != nil {
+		return err
+	}
+	vars := templateVars(cfg.Vars)
+	if cfg.ContentDir != "" {
+		filled, err := tree.FillContent(treeforge.OSFS{}, cfg.ContentDir, vars)
+		if err != nil {
+			return err
+		}
+		if cfg.Verbose {
+			fmt.Printf("Filled %d files from %s\n", filled, cfg.ContentDir)
+		}
+	}
+	if in != nil && in.content != nil {
+		filled, err := tree.FillContent(in.content, in.dir, vars)
+		if err != nil {
+			return err
+		}
+		if cfg.Verbose {
+			fmt.Printf("Filled %d files from the template\n", filled)
+		}
+	}
+	return nil
+}
+
+// contentSource fills in file contents from --content-dir as entries
+// stream past.
+type contentSource struct {
+	src  treeforge.EntrySource
+	dir  string
+	vars map[string]any
+}
+
+func (s contentSource) Next() (treeforge.Entry, error) {
+	e, err := s.src.Next()
+	if err == nil {
+		_, err = treeforge.ReadContent(&e, treeforge.OSFS{}, s.dir, s.vars)
+	}
+	return e, err
+}
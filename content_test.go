@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestFillContent(t *testing.T) {
+	skeleton := t.TempDir()
+	writeFiles(t, skeleton, map[string]string{
+		"main.go":        "package main\n",
+		"go.mod.tmpl":    "module {{ .Module }}\n{{ if .Docker }}// docker\n{{ end }}",
+		"docs/README.md": "from the content dir\n",
+	})
+	template := treeforge.NewMemFS()
+	if err := template.MkdirAll("docs", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"docs/README.md": "from the template\n", "Makefile": "all:\n"} {
+		if err := template.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree, err := treeforge.ParseLines([]string{"app/", "├─ docs/", "│  └─ README.md", "├─ main.go", "├─ go.mod", "├─ Makefile", "└─ empty.txt"}, treeforge.Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	cfg := defaultConfig()
+	cfg.ContentDir = skeleton
+	cfg.Vars = map[string]string{"Module": "example.com/app", "Docker": "false"}
+	if err := fillContent(tree, &input{content: template}, cfg); err != nil {
+		t.Fatalf("fillContent() unexpected error: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, e := range tree.Entries {
+		if e.Kind == treeforge.KindFile {
+			got[filepath.ToSlash(e.Path)] = string(e.Content)
+		}
+	}
+	want := map[string]string{
+		"docs/README.md": "from the content dir\n",
+		"main.go":        "package main\n",
+		"go.mod":         "module example.com/app\n",
+		"Makefile":       "all:\n",
+		"empty.txt":      "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contents = %v, want %v", got, want)
+	}
+
+	cfg.ContentDir = filepath.Join(skeleton, "main.go")
+	if err := fillContent(tree, nil, cfg); err == nil {
+		t.Errorf("fillContent() with a file as --content-dir expected error")
+	}
+}
+
+func TestConfigVars(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+	writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), "content-dir: skeleton\nvars:\n  Module: example.com/app\n  Docker: \"true\"\n")
+
+	cfg, err := loadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cfg.bindFlags(fs)
+	if err := fs.Parse([]string{"--var", "Docker=false", "--var", "Name=api=v2"}); err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	cfg.markFlags(fs)
+
+	if want := filepath.Join(tmpDir, "skeleton"); cfg.ContentDir != want {
+		t.Errorf("ContentDir = %q, want %q", cfg.ContentDir, want)
+	}
+	want := map[string]string{"Module": "example.com/app", "Docker": "false", "Name": "api=v2"}
+	if !reflect.DeepEqual(cfg.Vars, want) {
+		t.Errorf("Vars = %v, want %v", cfg.Vars, want)
+	}
+	if got := configFields["vars"].get(cfg); got != "[Docker=false, Module=example.com/app, Name=api=v2]" {
+		t.Errorf("vars = %q", got)
+	}
+	if cfg.Source["vars"] != sourceFlag {
+		t.Errorf("vars source = %q, want %q", cfg.Source["vars"], sourceFlag)
+	}
+	if data := templateVars(cfg.Vars); data["Docker"] != false || data["Name"] != "api=v2" {
+		t.Errorf("templateVars() = %v", data)
+	}
+
+	// Conditions read booleans the way the config does; other values are
+	// non-empty strings and hold.
+	lines := []string{"app/", "├─ on.txt  # if: .On", "├─ off.txt  # if: .Off", "├─ yes.txt  # if: .Yes", "├─ no.txt  # if: .No", "└─ zero.txt  # if: .Zero"}
+	vars := templateVars(map[string]string{"On": "on", "Off": "OFF", "Yes": "yes", "No": "no", "Zero": "0"})
+	tree, err := treeforge.ParseLines(lines, treeforge.Options{Vars: vars})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	var kept []string
+	for _, e := range tree.Entries {
+		kept = append(kept, e.Path)
+	}
+	if want := []string{"on.txt", "yes.txt", "zero.txt"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("entries kept = %v, want %v", kept, want)
+	}
+
+	if err := fs.Parse([]string{"--var", "novalue"}); err == nil {
+		t.Errorf("Parse(--var novalue) expected error")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+const defaultGitMessage = "Initial commit from treeforge"
+
+// setupGit initializes a repository in base unless base is already inside
+// a work tree, and optionally commits the files the apply wrote. Nothing
+// else in the work tree is staged or committed.
+func setupGit(base string, results []treeforge.Result, commit bool, message string, verbose bool) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("--git needs a git executable in PATH: %w", err)
+	}
+
+	if out, err := gitOutput(base, "", "rev-parse", "--is-inside-work-tree"); err == nil && strings.TrimSpace(out) == "true" {
+		if verbose {
+			fmt.Printf("  [GIT]  %s is already in a repository\n", base)
+		}
+	} else {
+		if err := runGit(base, "init", "--quiet"); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Initialized git repository in %s\n", base)
+	}
+
+	if !commit {
+		return nil
+	}
+	paths, err := gitPaths(base, results)
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		fmt.Println("Git: no new files to commit")
+		return nil
+	}
+	// Pathspecs are taken literally, so names with glob characters match
+	// only themselves.
+	spec := ":(literal)" + strings.Join(paths, "\x00:(literal)")
+	if _, err := gitOutput(base, spec, "add", "--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+		return err
+	}
+	if changed, err := gitStaged(base, paths); err != nil || !changed {
+		if err == nil {
+			fmt.Println("Git: nothing to commit, the files are unchanged")
+		}
+		return err
+	}
+	if _, err := gitOutput(base, spec, "commit", "--quiet", "-m", message, "--pathspec-from-file=-", "--pathspec-file-nul"); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Committed: %s\n", message)
+	return nil
+}
+
+// gitPaths returns the files the apply wrote, relative to base, leaving
+// out those git ignores. Backups are not included.
+func gitPaths(base string, results []treeforge.Result) ([]string, error) {
+	var paths []string
+	for _, res := range results {
+		if res.Step.Entry.Kind != treeforge.KindFile || !written(res.Status) {
+			continue
+		}
+		target := res.Step.Target
+		if res.Status == treeforge.StatusRenamed {
+			target = res.Path
+		}
+		rel, err := filepath.Rel(base, target)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	// check-ignore exits with 1 when none of the paths is ignored.
+	out, err := gitOutput(base, strings.Join(paths, "\x00"), "check-ignore", "-z", "--stdin")
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return nil, err
+	}
+	ignored := map[string]bool{}
+	for _, p := range strings.Split(out, "\x00") {
+		ignored[p] = true
+	}
+	kept := paths[:0]
+	for _, p := range paths {
+		if !ignored[p] {
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
+
+func runGit(dir string, args ...string) error {
+	_, err := gitOutput(dir, "", args...)
+	return err
+}
+
+// gitOutput runs git in dir with stdin as its input and returns its
+// output.
+// gitStaged reports whether the index differs from HEAD for any of paths.
+func gitStaged(base string, paths []string) (bool, error) {
+	args := []string{"diff", "--cached", "--quiet", "--"}
+	for _, p := range paths {
+		args = append(args, ":(literal)"+p)
+	}
+	// diff --quiet exits with 1 when there are differences.
+	_, err := gitOutput(base, "", args...)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, err
+}
+
+func gitOutput(dir, stdin string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func setGitIdentity(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "treeforge")
+	t.Setenv("GIT_AUTHOR_EMAIL", "treeforge@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "treeforge")
+	t.Setenv("GIT_COMMITTER_EMAIL", "treeforge@example.com")
+}
+
+// commitAll commits everything in repo, initializing it first if needed.
+func commitAll(t *testing.T, repo, message string) {
+	t.Helper()
+	for _, args := range [][]string{{"init", "--quiet"}, {"add", "--all"}, {"commit", "--quiet", "-m", message}} {
+		if err := runGit(repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// gitLines returns the output lines of a git command run in dir.
+func gitLines(t *testing.T, dir string, args ...string) []string {
+	t.Helper()
+	out, err := gitOutput(dir, "", args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Fields(out)
+}
+
+// applyTree creates lines under parent and returns the plan and results.
+func applyTree(t *testing.T, parent string, lines ...string) (*treeforge.Plan, []treeforge.Result) {
+	t.Helper()
+	tree, err := treeforge.ParseLines(lines, treeforge.Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	plan := treeforge.NewPlan(tree, parent)
+	report, err := treeforge.Apply(context.Background(), plan, treeforge.OSFS{})
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	return plan, report.Results
+}
+
+func TestSetupGit(t *testing.T) {
+	setGitIdentity(t)
+	parent := t.TempDir()
+	writeConfigFile(t, filepath.Join(parent, "notes.txt"), "not from the tree\n")
+	writeConfigFile(t, filepath.Join(parent, ".gitignore"), "*.log\n")
+
+	// A "." tree is created in the parent itself, next to unrelated files.
+	plan, results := applyTree(t, parent, "./", "├─ src/", "│  └─ main.go", "├─ debug.log", "└─ README.md")
+	if err := setupGit(plan.Base, results, true, "scaffold", false); err != nil {
+		t.Fatalf("setupGit() unexpected error: %v", err)
+	}
+	got := gitLines(t, parent, "log", "--format=%s", "--name-only")
+	if want := []string{"scaffold", "README.md", "src/main.go"}; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("git log = %q, want %q", got, want)
+	}
+
+	// Running again with nothing new must not fail on init or commit
+	if err := setupGit(plan.Base, nil, true, "again", false); err != nil {
+		t.Errorf("setupGit() on existing repository: %v", err)
+	}
+}
+
+func TestSetupGitUnchanged(t *testing.T) {
+	setGitIdentity(t)
+	parent := t.TempDir()
+
+	// Re-applying with --force rewrites the same content, so nothing is
+	// staged and the second commit is skipped.
+	plan, results := applyTree(t, parent, "app/", "└─ main.go")
+	if err := setupGit(plan.Base, results, true, "scaffold", false); err != nil {
+		t.Fatalf("setupGit() unexpected error: %v", err)
+	}
+	plan.Force = true
+	report, err := treeforge.Apply(context.Background(), plan, treeforge.OSFS{})
+	if err != nil {
+		t.Fatalf("Apply() with Force unexpected error: %v", err)
+	}
+	if report.Results[0].Status != treeforge.StatusOverwritten {
+		t.Fatalf("Apply() with Force status = %s, want overwritten", report.Results[0].Status)
+	}
+	out := captureStdout(t, func() {
+		if err := setupGit(plan.Base, report.Results, true, "again", false); err != nil {
+			t.Errorf("setupGit() with unchanged files: %v", err)
+		}
+	})
+	if !strings.Contains(out, "nothing to commit") {
+		t.Errorf("setupGit() output = %q, want a nothing to commit note", out)
+	}
+	if got := gitLines(t, plan.Base, "log", "--format=%s"); strings.Join(got, " ") != "scaffold" {
+		t.Errorf("git log = %q, want only the scaffold commit", got)
+	}
+}
+
+func TestSetupGitInsideWorkTree(t *testing.T) {
+	setGitIdentity(t)
+	outer := t.TempDir()
+	writeConfigFile(t, filepath.Join(outer, "README.md"), "outer\n")
+	commitAll(t, outer, "outer")
+	writeConfigFile(t, filepath.Join(outer, "notes.txt"), "untracked\n")
+
+	plan, results := applyTree(t, outer, "app/", "└─ main.go")
+	if err := setupGit(plan.Base, results, true, "add app", false); err != nil {
+		t.Fatalf("setupGit() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(plan.Base, ".git")); !os.IsNotExist(err) {
+		t.Errorf("setupGit() created a nested repository in %s", plan.Base)
+	}
+	if got := gitLines(t, outer, "show", "--format=%s", "--name-only", "HEAD"); strings.Join(got, " ") != "add app app/main.go" {
+		t.Errorf("git show = %q, want the app commit with app/main.go only", got)
+	}
+	if got := gitLines(t, outer, "status", "--porcelain"); strings.Join(got, " ") != "?? notes.txt" {
+		t.Errorf("git status = %q, want notes.txt left untracked", got)
+	}
+}
+
+func TestRunGitError(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	if err := runGit(t.TempDir(), "no-such-command"); err == nil {
+		t.Errorf("runGit() expected error for unknown subcommand")
+	}
+}
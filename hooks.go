@@ -0,0 +1,351 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// defaultHookTimeout bounds a hook that sets no timeout of its own.
+const defaultHookTimeout = time.Minute
+
+// Hook is a command from the config run after an apply. With Match set it
+// runs once for every written entry whose path matches; otherwise it runs
+// once after the whole apply.
+type Hook struct {
+	// Match is a glob matched against the entry's name, or against its
+	// path from the root if it contains a slash. A trailing slash matches
+	// directories only.
+	Match string
+
+	// Run is a shell command. {path} is replaced with the entry's
+	// absolute path and {root} with the root directory, both quoted.
+	Run string
+
+	Timeout time.Duration
+}
+
+func (h Hook) String() string {
+	if h.Match == "" {
+		return h.Run
+	}
+	return h.Match + " → " + h.Run
+}
+
+// configHooks reads the hooks key: a list of mappings with run and
+// optional match and timeout keys.
+func configHooks(v any) ([]Hook, error) {
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a list of hooks")
+	}
+	hooks := make([]Hook, 0, len(items))
+	for i, item := range items {
+		h, err := configHook(item)
+		if err != nil {
+			return nil, fmt.Errorf("hook %d: %w", i+1, err)
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, nil
+}
+
+func configHook(v any) (Hook, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return Hook{}, fmt.Errorf("expected match/run/timeout keys")
+	}
+	h := Hook{Timeout: defaultHookTimeout}
+	for key, value := range m {
+		s, err := configString(value)
+		if err != nil {
+			return Hook{}, fmt.Errorf("%s: %w", key, err)
+		}
+		switch key {
+		case "match":
+			_, err = filepath.Match(strings.TrimSuffix(s, "/"), "")
+			h.Match = s
+		case "run":
+			h.Run = s
+		case "timeout":
+			h.Timeout, err = parseHookTimeout(s)
+		default:
+			err = fmt.Errorf("unknown key")
+		}
+		if err != nil {
+			return Hook{}, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if strings.TrimSpace(h.Run) == "" {
+		return Hook{}, fmt.Errorf("run is required")
+	}
+	return h, nil
+}
+
+// parseHookTimeout accepts a Go duration ("30s", "2m") or plain seconds.
+func parseHookTimeout(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		s = strconv.Itoa(n) + "s"
+	}
+	d, err := time.ParseDuration(s)
+	if err == nil && d <= 0 {
+		err = fmt.Errorf("must be positive")
+	}
+	return d, err
+}
+
+// matches reports whether the hook applies to the entry written for res.
+func (h Hook) matches(res treeforge.Result) bool {
+	pattern, dirOnly := strings.CutSuffix(h.Match, "/")
+	if h.Match == "" || (dirOnly && res.Step.Entry.Kind != treeforge.KindDir) {
+		return false
+	}
+	name := filepath.ToSlash(res.Step.Entry.Path)
+	if !strings.Contains(pattern, "/") {
+		name = filepath.Base(name)
+	}
+	ok, _ := filepath.Match(pattern, name)
+	return ok
+}
+
+// written reports whether an apply put the entry's content on disk, which
+// is what per-entry hooks react to.
+func written(st treeforge.Status) bool {
+	switch st {
+	case treeforge.StatusCreated, treeforge.StatusFilled, treeforge.StatusOverwritten,
+		treeforge.StatusBackedUp, treeforge.StatusMerged, treeforge.StatusRenamed:
+		return true
+	}
+	return false
+}
+
+// hookRun is one command to run, with what it runs for.
+type hookRun struct {
+	hook Hook
+	res  *treeforge.Result // nil for run-level hooks
+}
+
+// hookFailure is a hook that failed or timed out, with the tail of its
+// output.
+type hookFailure struct {
+	run    hookRun
+	err    error
+	output string
+}
+
+// pendingHooks lists the commands an apply with these results triggers:
+// per-entry hooks in plan order, then run-level hooks.
+func pendingHooks(hooks []Hook, results []treeforge.Result) []hookRun {
+	var runs []hookRun
+	for i := range results {
+		if !written(results[i].Status) {
+			continue
+		}
+		for _, h := range hooks {
+			if h.matches(results[i]) {
+				runs = append(runs, hookRun{hook: h, res: &results[i]})
+			}
+		}
+	}
+	for _, h := range hooks {
+		if h.Match == "" {
+			runs = append(runs, hookRun{hook: h})
+		}
+	}
+	return runs
+}
+
+// countWritten returns how many of results put an entry on disk.
+func countWritten(results []treeforge.Result) int {
+	n := 0
+	for _, res := range results {
+		if written(res.Status) {
+			n++
+		}
+	}
+	return n
+}
+
+// wantsResult reports whether a per-entry hook runs for res, which is all
+// a streaming apply needs to keep.
+func wantsResult(hooks []Hook, res treeforge.Result) bool {
+	if !written(res.Status) {
+		return false
+	}
+	for _, h := range hooks {
+		if h.matches(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks runs the hooks triggered by results, one at a time in the
+// root directory, and reports how many ran and which failed. created is
+// the number of entries the apply wrote. A canceled ctx stops it before
+// the next hook.
+func runHooks(ctx context.Context, hooks []Hook, plan *treeforge.Plan, results []treeforge.Result, created int, verbose bool) (int, []hookFailure) {
+	root, err := filepath.Abs(plan.Base)
+	if err != nil {
+		root = plan.Base
+	}
+
+	ran := 0
+	var failures []hookFailure
+	for _, r := range pendingHooks(hooks, results) {
+		if ctx.Err() != nil {
+			break
+		}
+		ran++
+		out, err := r.exec(ctx, root, created)
+		if verbose {
+			fmt.Printf("  [HOOK] %s%s\n", r.label(root), failedNote(err))
+		}
+		if err != nil {
+			failures = append(failures, hookFailure{run: r, err: err, output: lastLines(out, 5)})
+		}
+	}
+	return ran, failures
+}
+
+func (r hookRun) label(root string) string {
+	if r.res == nil {
+		return r.hook.Run
+	}
+	return fmt.Sprintf("%s (%s)", r.hook.Run, r.path(root))
+}
+
+// path is the absolute path of the file the hook runs for.
+func (r hookRun) path(root string) string {
+	p := r.res.Step.Target
+	if r.res.Status == treeforge.StatusRenamed {
+		p = r.res.Path
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+	return filepath.Join(root, r.res.Step.Entry.Path)
+}
+
+// exec runs the hook's command with the run described in TREEFORGE_*
+// environment variables, returning its combined output.
+func (r hookRun) exec(ctx context.Context, root string, created int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.hook.Timeout)
+	defer cancel()
+
+	vars := []string{"{root}", shellQuote(root)}
+	env := []string{"TREEFORGE_ROOT=" + root, "TREEFORGE_CREATED=" + strconv.Itoa(created)}
+	if r.res != nil {
+		path := r.path(root)
+		vars = append(vars, "{path}", shellQuote(path))
+		env = append(env,
+			"TREEFORGE_PATH="+path,
+			"TREEFORGE_ENTRY="+filepath.ToSlash(r.res.Step.Entry.Path),
+			"TREEFORGE_KIND="+r.res.Step.Entry.Kind.String(),
+			"TREEFORGE_STATUS="+r.res.Status.String())
+	}
+	cmd := shellCommand(ctx, strings.NewReplacer(vars...).Replace(r.hook.Run))
+	cmd.Dir = root
+	// Children that outlive a killed shell must not keep us waiting.
+	cmd.WaitDelay = time.Second
+	cmd.Env = append(os.Environ(), env...)
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", r.hook.Timeout)
+	}
+	return out.Bytes(), err
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// shellQuote quotes s for the shell hooks run in.
+func shellQuote(s string) string {
+	if runtime.GOOS == "windows" {
+		return `"` + s + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func failedNote(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf(" — failed: %v", err)
+}
+
+// lastLines returns up to n trailing lines of out.
+func lastLines(out []byte, n int) string {
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// enabledHooks returns the configured hooks unless --no-hooks was given.
+// warnIgnoredHooks tells the user about hooks left out of a project
+// config found in a parent directory.
+func warnIgnoredHooks(w io.Writer, cfg *Config, flags *cliFlags) {
+	if cfg.IgnoredHooks != "" && !flags.noHooks {
+		fmt.Fprintf(w, "Warning: ignoring the hooks in %s; run treeforge in %s to use them\n", cfg.IgnoredHooks, filepath.Dir(cfg.IgnoredHooks))
+	}
+}
+
+func enabledHooks(cfg *Config, flags *cliFlags) []Hook {
+	if flags.noHooks {
+		return nil
+	}
+	return cfg.Hooks
+}
+
+// afterApply runs the hooks for a finished apply and prints their
+// summary. Failed hooks make the run fail once all have run.
+func afterApply(ctx context.Context, hooks []Hook, plan *treeforge.Plan, results []treeforge.Result, created int, verbose bool) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+	ran, failures := runHooks(ctx, hooks, plan, results, created, verbose)
+	if ran == 0 {
+		return nil
+	}
+	fmt.Printf("Hooks: %d run, %d failed\n", ran, len(failures))
+	for _, f := range failures {
+		fmt.Printf("  ✗ %s: %v\n", f.run.label(plan.Base), f.err)
+		if f.output != "" {
+			fmt.Printf("      %s\n", strings.ReplaceAll(f.output, "\n", "\n      "))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d hook(s) failed", len(failures))
+	}
+	return ctx.Err()
+}
+
+// printHookPlan lists the hooks a dry run's apply would trigger.
+func printHookPlan(hooks []Hook, results []treeforge.Result) {
+	for _, r := range pendingHooks(hooks, results) {
+		if r.res == nil {
+			fmt.Printf("  [HOOK] %s\n", r.hook.Run)
+		} else {
+			fmt.Printf("  [HOOK] %s (%s)\n", r.hook.Run, r.res.Step.Target)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestConfigHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+	writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), `hooks:
+  - match: "*.sh"
+    run: chmod +x {path}
+  - match: go.mod
+    run: go mod tidy
+    timeout: 2m
+  - run: git init
+    timeout: 5
+`)
+
+	cfg, err := loadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	want := []Hook{
+		{Match: "*.sh", Run: "chmod +x {path}", Timeout: defaultHookTimeout},
+		{Match: "go.mod", Run: "go mod tidy", Timeout: 2 * time.Minute},
+		{Run: "git init", Timeout: 5 * time.Second},
+	}
+	if !reflect.DeepEqual(cfg.Hooks, want) {
+		t.Errorf("Hooks = %+v, want %+v", cfg.Hooks, want)
+	}
+}
+
+func TestConfigHooksFromParent(t *testing.T) {
+	tmpDir := t.TempDir()
+	userConfig := filepath.Join(tmpDir, "user.yaml")
+	t.Setenv("TREEFORGE_CONFIG", userConfig)
+	writeConfigFile(t, userConfig, "hooks:\n  - run: make\n")
+	writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), "verbose: true\nhooks:\n  - run: make deploy\n")
+	sub := filepath.Join(tmpDir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+
+	// A config further up still sets options, but its hooks are left out
+	// and the user's hooks stay.
+	cfg, err := loadConfig(sub)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	if want := []Hook{{Run: "make", Timeout: defaultHookTimeout}}; !cfg.Verbose || !reflect.DeepEqual(cfg.Hooks, want) {
+		t.Errorf("loadConfig() Verbose = %v, Hooks = %+v, want true and %+v", cfg.Verbose, cfg.Hooks, want)
+	}
+	if cfg.Source["hooks"] != userConfig || cfg.IgnoredHooks != filepath.Join(tmpDir, projectConfigName) {
+		t.Errorf("loadConfig() hooks source = %q, ignored = %q", cfg.Source["hooks"], cfg.IgnoredHooks)
+	}
+	var b strings.Builder
+	warnIgnoredHooks(&b, cfg, &cliFlags{})
+	if !strings.Contains(b.String(), "run treeforge in "+tmpDir) {
+		t.Errorf("warnIgnoredHooks() = %q", b.String())
+	}
+
+	// In the config's own directory the hooks are used.
+	cfg, err = loadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("loadConfig() unexpected error: %v", err)
+	}
+	if len(cfg.Hooks) != 1 || cfg.Hooks[0].Run != "make deploy" || cfg.IgnoredHooks != "" {
+		t.Errorf("loadConfig() in the config's directory: Hooks = %+v, ignored = %q", cfg.Hooks, cfg.IgnoredHooks)
+	}
+}
+
+func TestConfigHooksErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "not a list", content: "hooks: make\n"},
+		{name: "missing run", content: "hooks:\n  - match: \"*.go\"\n"},
+		{name: "unknown key", content: "hooks:\n  - run: make\n    when: always\n"},
+		{name: "bad timeout", content: "hooks:\n  - run: make\n    timeout: soon\n"},
+		{name: "negative timeout", content: "hooks:\n  - run: make\n    timeout: -1s\n"},
+		{name: "bad pattern", content: "hooks:\n  - match: \"[\"\n    run: make\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			t.Setenv("TREEFORGE_CONFIG", filepath.Join(tmpDir, "missing.yaml"))
+			writeConfigFile(t, filepath.Join(tmpDir, projectConfigName), tt.content)
+
+			if _, err := loadConfig(tmpDir); err == nil {
+				t.Errorf("loadConfig() expected error but got none")
+			}
+		})
+	}
+}
+
+func TestHookMatches(t *testing.T) {
+	result := func(path string, kind treeforge.Kind) treeforge.Result {
+		return treeforge.Result{Step: treeforge.Step{Entry: treeforge.Entry{Path: filepath.FromSlash(path), Kind: kind}}}
+	}
+	tests := []struct {
+		match string
+		res   treeforge.Result
+		want  bool
+	}{
+		{"*.sh", result("app/scripts/build.sh", treeforge.KindFile), true},
+		{"*.sh", result("app/main.go", treeforge.KindFile), false},
+		{"app/*/build.sh", result("app/scripts/build.sh", treeforge.KindFile), true},
+		{"scripts/*.sh", result("app/scripts/build.sh", treeforge.KindFile), false},
+		{"scripts/", result("app/scripts", treeforge.KindDir), true},
+		{"scripts/", result("app/scripts", treeforge.KindFile), false},
+		{"", result("app/main.go", treeforge.KindFile), false},
+	}
+	for _, tt := range tests {
+		if got := (Hook{Match: tt.match}).matches(tt.res); got != tt.want {
+			t.Errorf("Hook{Match: %q}.matches(%s) = %v, want %v", tt.match, tt.res.Step.Entry.Path, got, tt.want)
+		}
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hooks in this test are POSIX shell commands")
+	}
+	parent := t.TempDir()
+	tree, err := treeforge.Parse(strings.NewReader("app/\n├─ run me.sh\n└─ main.go\n"), treeforge.Options{})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	plan := treeforge.NewPlan(tree, parent)
+	report, err := treeforge.Apply(context.Background(), plan, treeforge.OSFS{})
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	log := filepath.Join(t.TempDir(), "hooks.log")
+	hooks := []Hook{
+		{Match: "*.sh", Run: `echo "$TREEFORGE_ENTRY $TREEFORGE_KIND $TREEFORGE_STATUS" >> ` + log + ` && chmod +x {path}`, Timeout: time.Minute},
+		{Run: `echo "done $TREEFORGE_CREATED" >> ` + log, Timeout: time.Minute},
+		{Match: "main.go", Run: "echo broken; exit 3", Timeout: time.Minute},
+		{Run: "sleep 5", Timeout: 50 * time.Millisecond},
+	}
+
+	ran, failures := runHooks(context.Background(), hooks, plan, report.Results, countWritten(report.Results), false)
+	if ran != 4 || len(failures) != 2 {
+		t.Fatalf("runHooks() = %d run, %d failed; want 4 run, 2 failed", ran, len(failures))
+	}
+	if failures[0].output != "broken" {
+		t.Errorf("failure output = %q, want %q", failures[0].output, "broken")
+	}
+	if !strings.Contains(failures[1].err.Error(), "timed out") {
+		t.Errorf("failure error = %v, want a timeout", failures[1].err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Failed to read hook log: %v", err)
+	}
+	want := "run me.sh file created\ndone 2\n"
+	if string(data) != want {
+		t.Errorf("hook log = %q, want %q", data, want)
+	}
+	info, err := os.Stat(filepath.Join(parent, "app", "run me.sh"))
+	if err != nil || info.Mode()&0100 == 0 {
+		t.Errorf("{path} hook did not make the script executable: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// treeExt marks the tree file in an archive that does not name one.
+const treeExt = ".tree"
+
+// input is the tree read for -i, with the files stored beside it when it
+// came from a git repository or an archive.
+type input struct {
+	lines []string
+
+	// content holds the repository or archive files, and dir the tree
+	// file's directory within it, which entry paths are looked up under.
+	// content is nil for plain files and stdin.
+	content *treeforge.MemFS
+	dir     string
+
+	// include reads @include fragments from the same repository or
+	// archive; it is nil for plain files.
+	include func(path string) ([]string, error)
+}
+
+// readInput reads the tree named by -i: a file, stdin, a path inside a
+// local git repository as repo#ref:path/to/file.tree, or an archive as
+// file.tar.gz[#path/to/file.tree]. Nothing is fetched over the network.
+func readInput(spec string, verbose bool) (*input, error) {
+	src, sel, ok := packedInput(spec)
+	if !ok {
+		lines, err := processInput(spec, verbose)
+		return &input{lines: lines}, err
+	}
+
+	var files *treeforge.MemFS
+	var treePath string
+	var err error
+	if info, serr := os.Stat(src); serr == nil && info.IsDir() {
+		files, treePath, err = readGitInput(src, sel)
+	} else {
+		files, treePath, err = readArchiveInput(src, sel)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verbose {
+		fmt.Printf("Reading %s from %s\n", treePath, src)
+	}
+
+	data, err := files.ReadFile(treePath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: no tree file %s", src, filepath.ToSlash(treePath))
+	}
+	lines, err := readLines(bytes.NewReader(data))
+	in := &input{lines: lines, content: files, dir: filepath.Dir(treePath)}
+	in.include = func(path string) ([]string, error) {
+		data, err := files.ReadFile(filepath.Join(in.dir, path))
+		if err != nil {
+			return nil, err
+		}
+		return readLines(bytes.NewReader(data))
+	}
+	return in, err
+}
+
+// includeFiles returns an Options.Include that reads fragments from disk,
+// relative to dir.
+func includeFiles(dir string) func(path string) ([]string, error) {
+	return func(path string) ([]string, error) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readLines(f)
+	}
+}
+
+// packedInput splits an -i value naming a git repository or an archive
+// into its path and the selector after '#'. It reports false for
+// anything else, including existing files whose names contain '#'.
+func packedInput(spec string) (src, sel string, ok bool) {
+	if spec == "" {
+		return "", "", false
+	}
+	src = spec
+	if _, err := os.Stat(spec); err != nil {
+		i := strings.LastIndex(spec, "#")
+		if i < 0 {
+			return "", "", false
+		}
+		src, sel = spec[:i], spec[i+1:]
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return "", "", false
+	}
+	if info.IsDir() {
+		return src, sel, true
+	}
+	if _, err := treeforge.ArchiveFormatFromName(src); err == nil {
+		return src, sel, true
+	}
+	return "", "", false
+}
+
+// readGitInput reads the directory holding the tree file at ref:path in
+// the repository at repo, using git archive so that bare repositories
+// and any ref work without a checkout.
+func readGitInput(repo, sel string) (*treeforge.MemFS, string, error) {
+	ref, treePath, ok := strings.Cut(sel, ":")
+	if !ok || treePath == "" {
+		return nil, "", fmt.Errorf("%s is a directory; name a tree file in a git repository as %s#ref:path/to/file%s", repo, repo, treeExt)
+	}
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if strings.HasPrefix(ref, "-") {
+		return nil, "", fmt.Errorf("%s is not a git ref", ref)
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, "", fmt.Errorf("reading from a git repository needs a git executable in PATH: %w", err)
+	}
+
+	treePath = path.Clean(treePath)
+	// After --end-of-options neither the ref nor the directory can be
+	// read as an option.
+	args := []string{"-C", repo, "archive", "--format=tar", "--end-of-options", ref}
+	if dir := path.Dir(treePath); dir != "." {
+		args = append(args, dir)
+	}
+	cmd := exec.Command("git", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, "", fmt.Errorf("reading %s from %s: %s", sel, repo, msg)
+	}
+
+	files, err := readTar(&stdout)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s from %s: %w", sel, repo, err)
+	}
+	return files, filepath.FromSlash(treePath), nil
+}
+
+// readArchiveInput reads a .tar, .tar.gz or .zip file. The tree file is
+// sel if given, or else the archive's only *.tree file.
+func readArchiveInput(name, sel string) (*treeforge.MemFS, string, error) {
+	format, err := treeforge.ArchiveFormatFromName(name)
+	if err != nil {
+		return nil, "", err
+	}
+	files, err := readArchive(name, format)
+	if err != nil {
+		return nil, "", fmt.Errorf("reading %s: %w", name, err)
+	}
+	if sel != "" {
+		return files, filepath.FromSlash(path.Clean(sel)), nil
+	}
+
+	var found []string
+	for _, p := range files.Paths() {
+		if strings.HasSuffix(p, treeExt) {
+			found = append(found, filepath.ToSlash(p))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, "", fmt.Errorf("%s has no *%s file; name the tree file as %s#path/to/file", name, treeExt, name)
+	case 1:
+		return files, filepath.FromSlash(found[0]), nil
+	}
+	return nil, "", fmt.Errorf("%s has several *%s files (%s); pick one as %s#path/to/file", name, treeExt, strings.Join(found, ", "), name)
+}
+
+func readArchive(name string, format treeforge.ArchiveFormat) (*treeforge.MemFS, error) {
+	if format == treeforge.ArchiveZip {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return readZip(&zr.Reader)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if format == treeforge.ArchiveTarGz {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readTar(r)
+}
+
+// readTar loads the regular files of a tar stream into memory. Links and
+// names that would leave the archive are skipped.
+func readTar(r io.Reader) (*treeforge.MemFS, error) {
+	files := treeforge.NewMemFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		if err := addArchiveFile(files, hdr.Name, data); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readZip loads the regular files of a zip archive into memory, like
+// readTar.
+func readZip(zr *zip.Reader) (*treeforge.MemFS, error) {
+	files := treeforge.NewMemFS()
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		if err := addArchiveFile(files, f.Name, data); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func addArchiveFile(files *treeforge.MemFS, name string, data []byte) error {
+	name = filepath.FromSlash(path.Clean(strings.TrimPrefix(name, "./")))
+	if !filepath.IsLocal(name) {
+		return nil
+	}
+	if err := files.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return files.WriteFile(name, data, 0644)
+}
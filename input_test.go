@@ -0,0 +1,221 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// templateFiles is a tree file with content for one of its entries, laid
+// out the way a template repository or archive holds them.
+var templateFiles = map[string]string{
+	"services/api/api.tree":    "api/\n├─ cmd/\n│  └─ main.go\n└─ README.md\n",
+	"services/api/cmd/main.go": "package main\n",
+	"services/other/README.md": "not this one\n",
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		writeConfigFile(t, filepath.Join(dir, filepath.FromSlash(name)), data)
+	}
+}
+
+// checkTemplateInput checks that in holds the api tree and content from
+// templateFiles.
+func checkTemplateInput(t *testing.T, in *input, wantLines int) {
+	t.Helper()
+	if len(in.lines) != wantLines {
+		t.Fatalf("lines = %q, want %d lines", in.lines, wantLines)
+	}
+	tree, err := treeforge.ParseLines(in.lines, treeforge.Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	if filled, err := tree.FillContent(in.content, in.dir, nil); err != nil || filled != 1 {
+		t.Fatalf("FillContent() = %d, %v; want 1 file filled", filled, err)
+	}
+	got := map[string]string{}
+	for _, e := range tree.Entries {
+		if e.Content != nil {
+			got[filepath.ToSlash(e.Path)] = string(e.Content)
+		}
+	}
+	if want := map[string]string{"cmd/main.go": "package main\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("contents = %v, want %v", got, want)
+	}
+}
+
+func TestReadInputGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("GIT_AUTHOR_NAME", "treeforge")
+	t.Setenv("GIT_AUTHOR_EMAIL", "treeforge@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "treeforge")
+	t.Setenv("GIT_COMMITTER_EMAIL", "treeforge@example.com")
+
+	repo := t.TempDir()
+	writeFiles(t, repo, templateFiles)
+	commitAll(t, repo, "templates")
+	if err := runGit(repo, "tag", "v2"); err != nil {
+		t.Fatalf("git tag: %v", err)
+	}
+	// The working tree and HEAD move on; v2 must still be read as tagged.
+	writeFiles(t, repo, map[string]string{"services/api/api.tree": "api/\n└─ cmd/\n"})
+	commitAll(t, repo, "shrink")
+
+	in, err := readInput(repo+"#v2:services/api/api.tree", false)
+	if err != nil {
+		t.Fatalf("readInput() unexpected error: %v", err)
+	}
+	checkTemplateInput(t, in, 4)
+
+	for _, spec := range []string{repo, repo + "#v2", repo + "#v9:services/api/api.tree", repo + "#v2:services/none.tree"} {
+		if _, err := readInput(spec, false); err == nil {
+			t.Errorf("readInput(%q) expected error", strings.TrimPrefix(spec, repo))
+		}
+	}
+
+	// A ref that looks like an option must not reach git as one.
+	out := filepath.Join(t.TempDir(), "out.tar")
+	if _, err := readInput(repo+"#--output="+out+":services/api/api.tree", false); err == nil {
+		t.Errorf("readInput() with an option as ref expected error")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("readInput() with an option as ref wrote %s", out)
+	}
+}
+
+func TestReadInputArchive(t *testing.T) {
+	for _, name := range []string{"templates.tar.gz", "templates.zip"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			writeTestArchive(t, path, templateFiles)
+
+			in, err := readInput(path, false)
+			if err != nil {
+				t.Fatalf("readInput() unexpected error: %v", err)
+			}
+			checkTemplateInput(t, in, 4)
+
+			if _, err := readInput(path+"#services/api/cmd/none.tree", false); err == nil {
+				t.Errorf("readInput() of a missing tree file expected error")
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "two.tar")
+	writeTestArchive(t, path, map[string]string{"a.tree": "a/\n", "b/b.tree": "b/\n"})
+	if _, err := readInput(path, false); err == nil || !strings.Contains(err.Error(), "a.tree, b/b.tree") {
+		t.Errorf("readInput() of an archive with two tree files = %v, want an error naming both", err)
+	}
+	in, err := readInput(path+"#b/b.tree", false)
+	if err != nil || !reflect.DeepEqual(in.lines, []string{"b/"}) {
+		t.Errorf("readInput(#b/b.tree) = %v, %v; want the b tree", in, err)
+	}
+}
+
+func TestPackedInput(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"plain.tree": "a/\n", "odd#name.tree": "a/\n", "t.zip": "", "repo/.git/HEAD": ""})
+	tests := []struct {
+		spec     string
+		src, sel string
+		ok       bool
+	}{
+		{"plain.tree", "", "", false},
+		{"odd#name.tree", "", "", false},
+		{"t.zip", "t.zip", "", true},
+		{"t.zip#x/y.tree", "t.zip", "x/y.tree", true},
+		{"repo#main:a.tree", "repo", "main:a.tree", true},
+		{"missing.zip#a.tree", "", "", false},
+	}
+	for _, tt := range tests {
+		spec := filepath.Join(dir, tt.spec)
+		src, sel, ok := packedInput(spec)
+		if tt.src != "" {
+			tt.src = filepath.Join(dir, tt.src)
+		}
+		if src != tt.src || sel != tt.sel || ok != tt.ok {
+			t.Errorf("packedInput(%q) = %q, %q, %v; want %q, %q, %v", tt.spec, src, sel, ok, tt.src, tt.sel, tt.ok)
+		}
+	}
+}
+
+// writeTestArchive packs files into an archive at path, using the format
+// its name implies.
+func writeTestArchive(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	format, err := treeforge.ArchiveFormatFromName(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	defer f.Close()
+	afs := treeforge.NewArchiveFS(f, format)
+	for name, data := range files {
+		name = filepath.FromSlash(name)
+		if err := afs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := afs.WriteFile(name, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := afs.Close(); err != nil {
+		t.Fatalf("Failed to write archive: %v", err)
+	}
+}
+
+func TestReadInputInclude(t *testing.T) {
+	files := map[string]string{
+		"app.tree":        "app/\n├─ @include frag/ci.tree\n└─ main.go\n",
+		"frag/ci.tree":    "ci/\n└─ @include steps.tree\n",
+		"frag/steps.tree": "└─ test.yml\n",
+	}
+	want := []string{"ci", "ci/test.yml", "main.go"}
+	paths := func(t *testing.T, in *input, include func(string) ([]string, error)) []string {
+		t.Helper()
+		tree, err := treeforge.ParseLines(in.lines, treeforge.Options{Include: include})
+		if err != nil {
+			t.Fatalf("ParseLines() unexpected error: %v", err)
+		}
+		var got []string
+		for _, e := range tree.Entries {
+			got = append(got, filepath.ToSlash(e.Path))
+		}
+		return got
+	}
+
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	in, err := readInput(filepath.Join(dir, "app.tree"), false)
+	if err != nil {
+		t.Fatalf("readInput() unexpected error: %v", err)
+	}
+	if in.include != nil {
+		t.Errorf("include is set for a plain file")
+	}
+	if got := paths(t, in, includeFiles(dir)); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries from disk = %v, want %v", got, want)
+	}
+
+	archive := filepath.Join(t.TempDir(), "templates.tar.gz")
+	writeTestArchive(t, archive, files)
+	in, err = readInput(archive+"#app.tree", false)
+	if err != nil {
+		t.Fatalf("readInput() unexpected error: %v", err)
+	}
+	if got := paths(t, in, in.include); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries from the archive = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// key is a decoded keypress: a printable character as itself, or one of
+// the named keys below.
+type key string
+
+const (
+	keyUp        key = "<up>"
+	keyDown      key = "<down>"
+	keyLeft      key = "<left>"
+	keyRight     key = "<right>"
+	keyEnter     key = "<enter>"
+	keyEsc       key = "<esc>"
+	keyBackspace key = "<backspace>"
+	keyCtrlC     key = "<ctrl-c>"
+)
+
+// decodeKeys splits raw terminal input into keys.
+func decodeKeys(b []byte) []key {
+	arrows := map[byte]key{'A': keyUp, 'B': keyDown, 'C': keyRight, 'D': keyLeft}
+	var keys []key
+	for len(b) > 0 {
+		switch {
+		case len(b) >= 3 && b[0] == 0x1b && (b[1] == '[' || b[1] == 'O') && arrows[b[2]] != "":
+			keys, b = append(keys, arrows[b[2]]), b[3:]
+			continue
+		case b[0] == 0x1b:
+			keys = append(keys, keyEsc)
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, keyEnter)
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, keyBackspace)
+		case b[0] == 0x03:
+			keys = append(keys, keyCtrlC)
+		case b[0] < 0x20:
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys, b = append(keys, key(string(r))), b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// reviewItem is one entry in the review list.
+type reviewItem struct {
+	entry     treeforge.Entry
+	name      string // base name, as renamed by the user
+	parent    int    // index of the parent directory's item, or -1
+	depth     int
+	included  bool
+	collapsed bool
+}
+
+// review is the state of the --interactive entry list. It is driven by
+// handle and drawn by render, so it can be exercised without a terminal.
+type review struct {
+	items    []reviewItem
+	base     string
+	cursor   int
+	editing  bool
+	input    []rune
+	confirm  bool
+	message  string
+	done     bool
+	accepted bool
+}
+
+func newReview(entries []treeforge.Entry, base string) *review {
+	rv := &review{base: base}
+	dirs := map[string]int{}
+	for i, e := range entries {
+		parent, ok := dirs[filepath.Dir(e.Path)]
+		if !ok {
+			parent = -1
+		}
+		rv.items = append(rv.items, reviewItem{
+			entry:    e,
+			name:     filepath.Base(e.Path),
+			parent:   parent,
+			depth:    strings.Count(e.Path, string(filepath.Separator)),
+			included: true,
+		})
+		if e.Kind == treeforge.KindDir {
+			dirs[e.Path] = i
+		}
+	}
+	return rv
+}
+
+// handle applies one keypress.
+func (rv *review) handle(k key) {
+	rv.message = ""
+	switch {
+	case rv.editing:
+		rv.handleEdit(k)
+	case rv.confirm:
+		rv.confirm = false
+		if k == "y" || k == "Y" {
+			rv.done, rv.accepted = true, true
+		}
+	default:
+		rv.handleList(k)
+	}
+}
+
+func (rv *review) handleList(k key) {
+	switch k {
+	case keyEnter:
+		rv.confirm = true
+		return
+	case "q", keyEsc, keyCtrlC:
+		rv.done = true
+		return
+	}
+	if len(rv.items) == 0 {
+		return
+	}
+
+	switch k {
+	case keyUp, "k":
+		rv.move(-1)
+	case keyDown, "j":
+		rv.move(1)
+	case keyLeft, "h":
+		rv.fold(true)
+	case keyRight, "l":
+		rv.fold(false)
+	case " ":
+		rv.toggle(rv.cursor)
+	case "r":
+		rv.editing, rv.input = true, []rune(rv.items[rv.cursor].name)
+	}
+}
+
+func (rv *review) handleEdit(k key) {
+	switch k {
+	case keyEnter:
+		name := string(rv.input)
+		if err := checkRename(name); err != nil {
+			rv.message = err.Error()
+			return
+		}
+		rv.items[rv.cursor].name = name
+		rv.editing = false
+	case keyEsc, keyCtrlC:
+		rv.editing = false
+	case keyBackspace:
+		if len(rv.input) > 0 {
+			rv.input = rv.input[:len(rv.input)-1]
+		}
+	default:
+		if r, _ := utf8.DecodeRuneInString(string(k)); len(k) == utf8.RuneLen(r) && unicode.IsPrint(r) {
+			rv.input = append(rv.input, r)
+		}
+	}
+}
+
+// checkRename rejects names that would not stay a single path element.
+func checkRename(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("name cannot be empty")
+	case name == "." || name == "..":
+		return fmt.Errorf("name cannot be %q", name)
+	case strings.ContainsAny(name, `/\`):
+		return fmt.Errorf("name cannot contain a slash")
+	}
+	return nil
+}
+
+// visible reports whether no ancestor of item i is collapsed.
+func (rv *review) visible(i int) bool {
+	for p := rv.items[i].parent; p >= 0; p = rv.items[p].parent {
+		if rv.items[p].collapsed {
+			return false
+		}
+	}
+	return true
+}
+
+// move steps the cursor to the next visible item in direction dir.
+func (rv *review) move(dir int) {
+	for i := rv.cursor + dir; i >= 0 && i < len(rv.items); i += dir {
+		if rv.visible(i) {
+			rv.cursor = i
+			return
+		}
+	}
+}
+
+// fold collapses or expands the directory under the cursor. Collapsing
+// something that is not an open directory moves to its parent instead.
+func (rv *review) fold(collapse bool) {
+	it := &rv.items[rv.cursor]
+	if it.entry.Kind == treeforge.KindDir && it.collapsed != collapse {
+		it.collapsed = collapse
+		return
+	}
+	if collapse && it.parent >= 0 {
+		rv.cursor = it.parent
+	}
+}
+
+// toggle flips item i together with everything under it. Including an
+// entry also includes the directories above it.
+func (rv *review) toggle(i int) {
+	on := !rv.items[i].included
+	for j := i; j < len(rv.items); j++ {
+		if j == i || rv.isUnder(j, i) {
+			rv.items[j].included = on
+		}
+	}
+	if on {
+		for p := rv.items[i].parent; p >= 0; p = rv.items[p].parent {
+			rv.items[p].included = true
+		}
+	}
+}
+
+// isUnder reports whether item j lies inside directory item i.
+func (rv *review) isUnder(j, i int) bool {
+	for p := rv.items[j].parent; p >= 0; p = rv.items[p].parent {
+		if p == i {
+			return true
+		}
+	}
+	return false
+}
+
+// apply writes the selection back into tree: renamed paths for the
+// included entries, and the rest moved to tree.Excluded.
+func (rv *review) apply(tree *treeforge.Tree) {
+	paths := make([]string, len(rv.items))
+	tree.Entries = tree.Entries[:0]
+	for i, it := range rv.items {
+		dir := filepath.Dir(it.entry.Path)
+		if it.parent >= 0 {
+			dir = paths[it.parent]
+		}
+		paths[i] = filepath.Join(dir, it.name)
+
+		if !it.included {
+			tree.Excluded = append(tree.Excluded, treeforge.Exclusion{Entry: it.entry, Reason: "deselected"})
+			continue
+		}
+		e := it.entry
+		e.Path = paths[i]
+		tree.Entries = append(tree.Entries, e)
+	}
+}
+
+func (rv *review) count() (included, excluded int) {
+	for _, it := range rv.items {
+		if it.included {
+			included++
+		} else {
+			excluded++
+		}
+	}
+	return included, excluded
+}
+
+// render draws the list, scrolled to keep the cursor in view, and a
+// status line into a screen of the given height.
+func (rv *review) render(w io.Writer, height int) {
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+	fmt.Fprintf(w, "Review %s — ↑↓ move  space include  ←→ fold  r rename  enter apply  q quit\r\n\r\n", rv.base)
+
+	var rows []int
+	for i := range rv.items {
+		if rv.visible(i) {
+			rows = append(rows, i)
+		}
+	}
+	rv.renderRows(w, rows, max(height-4, 1))
+
+	fmt.Fprint(w, "\r\n")
+	included, excluded := rv.count()
+	switch {
+	case rv.editing:
+		fmt.Fprintf(w, "Rename to: %s█", string(rv.input))
+	case rv.confirm:
+		fmt.Fprintf(w, "Create %d entries (%d deselected) in %s? [y/N] ", included, excluded, rv.base)
+	case rv.message != "":
+		fmt.Fprintf(w, "%s", rv.message)
+	default:
+		fmt.Fprintf(w, "%d of %d entries selected", included, len(rv.items))
+	}
+}
+
+func (rv *review) renderRows(w io.Writer, rows []int, limit int) {
+	start := 0
+	for n, i := range rows {
+		if i == rv.cursor && n >= limit {
+			start = n - limit + 1
+		}
+	}
+	for _, i := range rows[start:min(start+limit, len(rows))] {
+		fmt.Fprintf(w, "%s\r\n", rv.row(i))
+	}
+}
+
+// row formats item i as a line of the list.
+func (rv *review) row(i int) string {
+	it := rv.items[i]
+	cursor, check, fold, name := "  ", "[ ]", "  ", it.name
+	if i == rv.cursor {
+		cursor = "> "
+	}
+	if it.included {
+		check = "[x]"
+	}
+	if it.entry.Kind == treeforge.KindDir {
+		fold, name = "▾ ", name+"/"
+		if it.collapsed {
+			fold = "▸ "
+		}
+	}
+	if orig := filepath.Base(it.entry.Path); it.name != orig {
+		name += fmt.Sprintf(" (was %s)", orig)
+	}
+	return cursor + check + " " + strings.Repeat("  ", it.depth) + fold + name
+}
+
+// reviewTree lets the user deselect and rename entries on the terminal
+// before anything is created. It reports whether they confirmed; the
+// tree is changed only then.
+func reviewTree(tree *treeforge.Tree, base string) (bool, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false, fmt.Errorf("--interactive needs a terminal: %w", err)
+	}
+	defer tty.Close()
+
+	restore, err := rawMode(tty)
+	if err != nil {
+		return false, err
+	}
+	fmt.Fprint(tty, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer func() {
+		fmt.Fprint(tty, "\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	rv := newReview(tree.Entries, base)
+	height := termHeight(tty)
+	buf := make([]byte, 64)
+	for !rv.done {
+		rv.render(tty, height)
+		n, err := tty.Read(buf)
+		if err != nil {
+			return false, err
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			rv.handle(k)
+		}
+	}
+
+	if rv.accepted {
+		rv.apply(tree)
+	}
+	return rv.accepted, nil
+}
+
+// rawMode switches tty to unbuffered input without echo using stty, and
+// returns a function that restores the previous settings.
+func rawMode(tty *os.File) (func(), error) {
+	saved, err := stty(tty, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("--interactive needs stty: %w", err)
+	}
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return nil, fmt.Errorf("setting terminal mode: %w", err)
+	}
+	return func() { stty(tty, saved) }, nil
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// termHeight returns the number of rows on tty, or 24 if unknown.
+func termHeight(tty *os.File) int {
+	out, err := stty(tty, "size")
+	if rows, _, ok := strings.Cut(out, " "); err == nil && ok {
+		if n, err := strconv.Atoi(rows); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 24
+}
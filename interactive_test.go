@@ -0,0 +1,129 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func reviewFixture() *treeforge.Tree {
+	return &treeforge.Tree{
+		Root: "myapp",
+		Entries: []treeforge.Entry{
+			{Path: "src", Kind: treeforge.KindDir},
+			{Path: filepath.Join("src", "main.go"), Kind: treeforge.KindFile},
+			{Path: filepath.Join("src", "junk.tmp"), Kind: treeforge.KindFile},
+			{Path: "docs", Kind: treeforge.KindDir},
+			{Path: filepath.Join("docs", "notes.md"), Kind: treeforge.KindFile},
+			{Path: "README.md", Kind: treeforge.KindFile},
+		},
+	}
+}
+
+// press feeds keys to rv, spelled as terminal input.
+func press(rv *review, input string) {
+	for _, k := range decodeKeys([]byte(input)) {
+		rv.handle(k)
+	}
+}
+
+func paths(entries []treeforge.Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, filepath.ToSlash(e.Path))
+	}
+	return out
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("\x1b[A\x1bOBj é\r\x7f\x1b\x03\x01"))
+	want := []key{keyUp, keyDown, "j", " ", "é", keyEnter, keyBackspace, keyEsc, keyCtrlC}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeKeys() = %q, want %q", got, want)
+	}
+}
+
+func TestReviewSelectAndRename(t *testing.T) {
+	tree := reviewFixture()
+	rv := newReview(tree.Entries, "myapp")
+
+	// Deselect src/junk.tmp, rename src/ to cmd/ and deselect docs/.
+	press(rv, "jj j")
+	press(rv, "kkkr\x7f\x7f\x7fcmd\r")
+	press(rv, "jjj ")
+	press(rv, "\ry")
+
+	if !rv.done || !rv.accepted {
+		t.Fatalf("review not accepted after confirming")
+	}
+	rv.apply(tree)
+
+	want := []string{"cmd", "cmd/main.go", "README.md"}
+	if got := paths(tree.Entries); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	if len(tree.Excluded) != 3 || tree.Excluded[0].Reason != "deselected" {
+		t.Errorf("Excluded = %v, want junk.tmp, docs and notes.md deselected", tree.Excluded)
+	}
+}
+
+func TestReviewToggleIncludesParents(t *testing.T) {
+	rv := newReview(reviewFixture().Entries, "myapp")
+	press(rv, " ")  // src/ and everything in it off
+	press(rv, "j ") // main.go back on, which needs src/
+
+	got := []bool{}
+	for _, it := range rv.items[:3] {
+		got = append(got, it.included)
+	}
+	if want := []bool{true, true, false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("included = %v, want %v", got, want)
+	}
+}
+
+func TestReviewFold(t *testing.T) {
+	rv := newReview(reviewFixture().Entries, "myapp")
+	press(rv, "\x1b[D") // collapse src/
+	press(rv, "j")
+	if rv.cursor != 3 {
+		t.Errorf("cursor = %d after moving past collapsed src/, want 3 (docs/)", rv.cursor)
+	}
+
+	var b strings.Builder
+	rv.render(&b, 24)
+	if strings.Contains(b.String(), "main.go") || !strings.Contains(b.String(), "▸ src/") {
+		t.Errorf("render() of collapsed src/:\n%s", b.String())
+	}
+
+	press(rv, "jh") // from notes.md, left goes to docs/
+	if rv.cursor != 3 {
+		t.Errorf("cursor = %d after left on a file, want its parent 3", rv.cursor)
+	}
+}
+
+func TestReviewRenameRejected(t *testing.T) {
+	rv := newReview(reviewFixture().Entries, "myapp")
+	press(rv, "r\x7f\x7f\x7fa/b\r")
+	if !rv.editing || !strings.Contains(rv.message, "slash") {
+		t.Errorf("rename to a/b: editing = %v, message = %q", rv.editing, rv.message)
+	}
+	press(rv, "\x1b")
+	if rv.editing || rv.items[0].name != "src" {
+		t.Errorf("escape did not cancel the rename")
+	}
+}
+
+func TestReviewCancel(t *testing.T) {
+	rv := newReview(reviewFixture().Entries, "myapp")
+	press(rv, "\rn")
+	if rv.done {
+		t.Errorf("declining the confirmation ended the review")
+	}
+	press(rv, "q")
+	if !rv.done || rv.accepted {
+		t.Errorf("q: done = %v, accepted = %v, want done without accepting", rv.done, rv.accepted)
+	}
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
 )
 
 func TestProcessInput(t *testing.T) {
@@ -62,164 +68,262 @@ func TestProcessInput(t *testing.T) {
 	}
 }
 
-func TestDetermineRootName(t *testing.T) {
-	tests := []struct {
-		name      string
-		rootName  string
-		firstLine string
-		expected  string
-	}{
-		{
-			name:      "explicit root name",
-			rootName:  "custom-root",
-			firstLine: "myapp/",
-			expected:  "custom-root",
-		},
-		{
-			name:      "extract from first line",
-			rootName:  "",
-			firstLine: "myapp/",
-			expected:  "myapp",
-		},
-		{
-			name:      "extract from first line without slash",
-			rootName:  "",
-			firstLine: "myapp",
-			expected:  "myapp",
-		},
-		{
-			name:      "empty first line falls back to output",
-			rootName:  "",
-			firstLine: "",
-			expected:  "output",
+func TestPrintDryRun(t *testing.T) {
+	tree := &treeforge.Tree{
+		Root: "test",
+		Entries: []treeforge.Entry{
+			{Path: "src", Kind: treeforge.KindDir},
+			{Path: filepath.Join("src", "main.go"), Kind: treeforge.KindFile},
+			{Path: "README.md", Kind: treeforge.KindFile},
 		},
-		{
-			name:      "whitespace only first line falls back to output",
-			rootName:  "",
-			firstLine: "   ",
-			expected:  "output",
+		Excluded: []treeforge.Exclusion{
+			{Entry: treeforge.Entry{Path: "dist", Kind: treeforge.KindDir}, Reason: "dist/"},
 		},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := determineRootName(tt.rootName, tt.firstLine)
-			if result != tt.expected {
-				t.Errorf("determineRootName() = %q, want %q", result, tt.expected)
-			}
-		})
+	parent := t.TempDir()
+	base := filepath.Join(parent, "test")
+	writeConfigFile(t, filepath.Join(base, "README.md"), "# test\n")
+	out := captureStdout(t, func() {
+		if err := printDryRun(treeforge.NewPlan(tree, parent), nil); err != nil {
+			t.Errorf("printDryRun() unexpected error: %v", err)
+		}
+	})
+
+	want := "=== Dry-run mode (use --apply to create files) ===\n" +
+		"Base: " + base + "\n\n" +
+		"  [DIR]  " + filepath.Join(base, "src") + "\n" +
+		"  [FILE] " + filepath.Join(base, "src", "main.go") + "\n" +
+		"  [SKIP] " + filepath.Join(base, "README.md") + " (already exists)\n" +
+		"  [EXCL] " + filepath.Join(base, "dist") + " (dist/)\n" +
+		"\nTotal: 1 directories, 2 files, 1 excluded\n"
+	if out != want {
+		t.Errorf("printDryRun() output:\n%s\nwant:\n%s", out, want)
+	}
+
+	// Dry-run must not touch the filesystem
+	if _, err := os.Stat(filepath.Join(base, "src")); !os.IsNotExist(err) {
+		t.Errorf("printDryRun() created %s", filepath.Join(base, "src"))
 	}
 }
 
-func TestPrintDryRun(t *testing.T) {
-	entries := []Entry{
-		{Path: "src", Kind: KindDir},
-		{Path: "src/main.go", Kind: KindFile},
-		{Path: "README.md", Kind: KindFile},
+// captureStdout returns what f prints to standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	f()
+	w.Close()
+	return <-done
+}
+
+func TestApplyEntries(t *testing.T) {
+	tmpDir := t.TempDir()
+	tree := &treeforge.Tree{
+		Root: "myapp",
+		Entries: []treeforge.Entry{
+			{Path: "src", Kind: treeforge.KindDir},
+			{Path: filepath.Join("src", "main.go"), Kind: treeforge.KindFile},
+		},
 	}
 
-	basePath := "/tmp/test"
+	// Verbose lines come out as each entry is created, before the
+	// plan's own callback runs for it.
+	plan := treeforge.NewPlan(tree, tmpDir)
+	calls := 0
+	plan.OnResult = func(res treeforge.Result) {
+		calls++
+		fmt.Printf("callback %s\n", filepath.Base(res.Step.Target))
+	}
+	out := captureStdout(t, func() {
+		if _, err := applyEntries(context.Background(), plan, true, false); err != nil {
+			t.Errorf("applyEntries() unexpected error: %v", err)
+		}
+	})
 
-	// This function would normally print to stdout
-	// We're testing that it doesn't panic and handles the entries correctly
-	printDryRun(basePath, entries)
+	src, main := filepath.Join(tmpDir, "myapp", "src"), filepath.Join(tmpDir, "myapp", "src", "main.go")
+	want := fmt.Sprintf("  [DIR]  %s\ncallback src\n  [FILE] %s\ncallback main.go\n", src, main)
+	if !strings.HasPrefix(out, want) {
+		t.Errorf("output = %q, want it to start %q", out, want)
+	}
+	if _, err := os.Stat(main); err != nil {
+		t.Errorf("applyEntries() did not create main.go: %v", err)
+	}
 
-	// No assertion needed - just checking it doesn't panic
+	plan.OnResult(treeforge.Result{Step: plan.Steps[0]})
+	if calls != 3 {
+		t.Errorf("plan callback called %d times, want 2 during the apply and the original restored", calls)
+	}
 }
 
-func TestCreateEntry(t *testing.T) {
+func TestApplyEntriesRollback(t *testing.T) {
 	tmpDir := t.TempDir()
+	// A file where the tree wants a directory makes the apply fail part-way.
+	if err := os.WriteFile(filepath.Join(tmpDir, "blocker"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create blocker: %v", err)
+	}
+	tree := &treeforge.Tree{
+		Root: ".",
+		Entries: []treeforge.Entry{
+			{Path: "src", Kind: treeforge.KindDir},
+			{Path: "src/main.go", Kind: treeforge.KindFile},
+			{Path: "blocker/file.txt", Kind: treeforge.KindFile},
+		},
+	}
+
+	if _, err := applyEntries(context.Background(), treeforge.NewPlan(tree, tmpDir), false, true); err == nil {
+		t.Fatal("applyEntries() expected error")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "src")); !os.IsNotExist(err) {
+		t.Errorf("applyEntries() did not roll back src")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "blocker")); err != nil {
+		t.Errorf("applyEntries() rolled back a file it did not create")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree.Root = "myapp"
+	_, err := applyEntries(ctx, treeforge.NewPlan(tree, tmpDir), false, true)
+	if err == nil || err.Error() != "interrupted" {
+		t.Errorf("applyEntries() after cancel: error = %v, want interrupted", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp")); !os.IsNotExist(err) {
+		t.Errorf("applyEntries() left the base directory behind after rollback")
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	tree := &treeforge.Tree{
+		Root:    "myapp",
+		Entries: []treeforge.Entry{{Path: "src/main.go", Kind: treeforge.KindFile}},
+	}
+
+	out := filepath.Join(tmpDir, "out.zip")
+	if err := writeArchive(tree, out, false); err != nil {
+		t.Fatalf("writeArchive() unexpected error: %v", err)
+	}
+	if info, err := os.Stat(out); err != nil || info.Size() == 0 {
+		t.Errorf("writeArchive() did not write %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp")); !os.IsNotExist(err) {
+		t.Errorf("writeArchive() created the tree on disk")
+	}
+
+	if err := writeArchive(tree, filepath.Join(tmpDir, "out.rar"), false); err == nil {
+		t.Errorf("writeArchive() expected error for unsupported format")
+	}
+}
+
+func TestEmitScript(t *testing.T) {
+	tree := &treeforge.Tree{
+		Root: "my app",
+		Entries: []treeforge.Entry{
+			{Path: "docs", Kind: treeforge.KindDir},
+			{Path: filepath.Join("docs", `it's "new".md`), Kind: treeforge.KindFile},
+			{Path: "main.go", Kind: treeforge.KindFile},
+		},
+	}
+	plan := treeforge.NewPlan(tree, "")
 
 	tests := []struct {
-		name        string
-		entry       Entry
-		basePath    string
-		force       bool
-		verbose     bool
-		expectError bool
+		format string
+		want   string
 	}{
 		{
-			name:        "create directory",
-			entry:       Entry{Path: "testdir", Kind: KindDir},
-			basePath:    tmpDir,
-			force:       false,
-			verbose:     false,
-			expectError: false,
-		},
-		{
-			name:        "create file",
-			entry:       Entry{Path: "testfile.txt", Kind: KindFile},
-			basePath:    tmpDir,
-			force:       false,
-			verbose:     false,
-			expectError: false,
+			format: "sh",
+			want: `#!/bin/sh
+# Generated by treeforge: creates my app
+set -e
+
+mkdir -p 'my app'
+mkdir -p 'my app/docs'
+[ -e 'my app/docs/it'\''s "new".md' ] || : > 'my app/docs/it'\''s "new".md'
+[ -e 'my app/main.go' ] || : > 'my app/main.go'
+`,
 		},
 		{
-			name:        "create nested file",
-			entry:       Entry{Path: "nested/deep/file.txt", Kind: KindFile},
-			basePath:    tmpDir,
-			force:       false,
-			verbose:     false,
-			expectError: false,
+			format: "powershell",
+			want: `# Generated by treeforge: creates my app
+$ErrorActionPreference = 'Stop'
+
+New-Item -ItemType Directory -Force -Path 'my app' | Out-Null
+New-Item -ItemType Directory -Force -Path 'my app/docs' | Out-Null
+if (-not (Test-Path -LiteralPath 'my app/docs/it''s "new".md')) { New-Item -ItemType File -Force -Path 'my app/docs/it''s "new".md' | Out-Null }
+if (-not (Test-Path -LiteralPath 'my app/main.go')) { New-Item -ItemType File -Force -Path 'my app/main.go' | Out-Null }
+`,
 		},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result, err := createEntry(tt.entry, tt.basePath, tt.force, tt.verbose)
-
-			if tt.expectError {
-				if err == nil {
-					t.Errorf("createEntry() expected error but got none")
+		t.Run(tt.format, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				if err := emitScript(plan, tt.format); err != nil {
+					t.Errorf("emitScript() unexpected error: %v", err)
 				}
-				return
-			}
-
-			if err != nil {
-				t.Errorf("createEntry() unexpected error: %v", err)
-				return
-			}
-
-			// Check that the result indicates success
-			if result != "created" && result != "skipped" {
-				t.Errorf("createEntry() got unexpected result: %s", result)
-			}
-
-			// Verify the file/directory was actually created
-			fullPath := filepath.Join(tt.basePath, tt.entry.Path)
-			if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-				t.Errorf("createEntry() did not create %s", fullPath)
+			})
+			if out != tt.want {
+				t.Errorf("emitScript() output:\n%s\nwant:\n%s", out, tt.want)
 			}
 		})
 	}
-}
-
-func TestCreateEntryExistingFile(t *testing.T) {
-	tmpDir := t.TempDir()
-	existingFile := filepath.Join(tmpDir, "existing.txt")
 
-	// Create an existing file
-	if err := os.WriteFile(existingFile, []byte("content"), 0644); err != nil {
-		t.Fatalf("Failed to create existing file: %v", err)
+	if err := emitScript(plan, "cmd"); err == nil {
+		t.Errorf("emitScript() expected error for unknown format")
 	}
+}
 
-	entry := Entry{Path: "existing.txt", Kind: KindFile}
+func TestLoadIgnore(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, ".gitignore"), []byte("*.log\n"), 0644); err != nil {
+		t.Fatalf("Failed to write .gitignore: %v", err)
+	}
 
-	// Test without force - should skip
-	result, err := createEntry(entry, tmpDir, false, false)
-	if err != nil {
-		t.Errorf("createEntry() unexpected error: %v", err)
+	tests := []struct {
+		name     string
+		noIgnore bool
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "gitignore pattern", path: "app.log", expected: true},
+		{name: "gitignore disabled", noIgnore: true, path: "app.log", expected: false},
+		{name: "exclude pattern", path: "build/out", expected: true},
+		{name: "exclude without ignore files", noIgnore: true, path: "build/out", expected: true},
+		{name: "git directory", noIgnore: true, path: ".git", isDir: true, expected: true},
+		{name: "regular file", path: "main.go", expected: false},
 	}
-	if result != "skipped" {
-		t.Errorf("createEntry() expected 'skipped', got %q", result)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ig, err := loadIgnore(base, []string{"build/"}, tt.noIgnore)
+			if err != nil {
+				t.Fatalf("loadIgnore() unexpected error: %v", err)
+			}
+			if got := ig.Match(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
 	}
+}
 
-	// Test with force - should overwrite
-	result, err = createEntry(entry, tmpDir, true, false)
-	if err != nil {
-		t.Errorf("createEntry() unexpected error: %v", err)
+func TestNormalizedNote(t *testing.T) {
+	if got := normalizedNote(treeforge.Entry{Path: "main.go"}); got != "" {
+		t.Errorf("normalizedNote() = %q, want empty", got)
 	}
-	if result != "created" {
-		t.Errorf("createEntry() expected 'created', got %q", result)
+	e := treeforge.Entry{Path: "main.go", Original: "ma\u200bin.go"}
+	if got, want := normalizedNote(e), ` (normalized from "ma\u200bin.go")`; got != want {
+		t.Errorf("normalizedNote() = %q, want %q", got, want)
 	}
 }
@@ -0,0 +1,153 @@
+package treeforge
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Status is the outcome of applying a single step.
+type Status int
+
+const (
+	StatusCreated Status = iota
+	StatusSkipped
+	StatusOverwritten
+	StatusBackedUp
+	StatusRenamed
+
+	// The statuses below apply to file entries that carry content and
+	// whose target already exists.
+
+	// StatusUnchanged: the file already had the entry's content.
+	StatusUnchanged
+	// StatusFilled: the file was empty and now has the entry's content.
+	StatusFilled
+	// StatusDiffers: the file has other content and was left alone.
+	StatusDiffers
+	// StatusMerged: both versions were written with conflict markers.
+	StatusMerged
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusCreated:
+		return "created"
+	case StatusSkipped:
+		return "skipped"
+	case StatusOverwritten:
+		return "overwritten"
+	case StatusBackedUp:
+		return "backed up"
+	case StatusRenamed:
+		return "renamed"
+	case StatusUnchanged:
+		return "unchanged"
+	case StatusFilled:
+		return "filled"
+	case StatusDiffers:
+		return "differs"
+	case StatusMerged:
+		return "merged"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Result records what happened to one step.
+type Result struct {
+	Step   Step
+	Status Status
+
+	// Path is the other file involved in a conflict: the backup for
+	// StatusBackedUp, or the file written instead of the target for
+	// StatusRenamed.
+	Path string
+}
+
+// Report lists the results of an Apply in plan order.
+type Report struct {
+	Results []Result
+}
+
+// Count returns the number of results with the given status.
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Apply creates the plan's directories and files on fsys. On error the
+// returned report covers the steps completed before the failure.
+//
+// With plan.Jobs above 1, steps run concurrently on that many workers and
+// fsys must be safe for concurrent use; see applyParallel.
+func Apply(ctx context.Context, plan *Plan, fsys FS) (*Report, error) {
+	report := &Report{}
+
+	if err := fsys.MkdirAll(plan.Base, 0755); err != nil {
+		return report, fmt.Errorf("creating base directory: %w", err)
+	}
+
+	if plan.Jobs > 1 {
+		return applyParallel(ctx, plan, fsys)
+	}
+
+	for _, step := range plan.Steps {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		res, err := createEntry(ctx, fsys, step, plan)
+		if err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, res)
+		if plan.OnResult != nil {
+			plan.OnResult(res)
+		}
+	}
+
+	return report, nil
+}
+
+// createEntry creates one directory or file, settling existing files as
+// plan says. Once started, an entry is always finished: ctx is only
+// consulted before anything is touched, so cancellation never leaves a
+// half-created entry behind.
+func createEntry(ctx context.Context, fsys FS, step Step, plan *Plan) (Result, error) {
+	res := Result{Step: step, Status: StatusCreated}
+	if err := ctx.Err(); err != nil {
+		return res, err
+	}
+	fullPath := step.Target
+
+	if step.Entry.Kind == KindDir {
+		if err := fsys.MkdirAll(fullPath, 0755); err != nil {
+			return res, fmt.Errorf("creating directory %s: %w", fullPath, err)
+		}
+		return res, nil
+	}
+
+	// Check if file exists
+	if _, err := fsys.Stat(fullPath); err == nil {
+		return resolveConflict(fsys, step, plan)
+	}
+	return res, writeFile(fsys, fullPath, step.Entry.Content)
+}
+
+// writeFile creates or replaces a file, and its parent directory if needed.
+func writeFile(fsys FS, path string, content []byte) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating directory for file %s: %w", path, err)
+	}
+
+	// Create the file, empty unless the entry carries content
+	if err := fsys.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("creating file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		entry       Entry
+		force       bool
+		expectError bool
+	}{
+		{
+			name:  "create directory",
+			entry: Entry{Path: "testdir", Kind: KindDir},
+		},
+		{
+			name:  "create file",
+			entry: Entry{Path: "testfile.txt", Kind: KindFile},
+		},
+		{
+			name:  "create nested file",
+			entry: Entry{Path: "nested/deep/file.txt", Kind: KindFile},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := Step{Entry: tt.entry, Target: filepath.Join(tmpDir, tt.entry.Path)}
+			result, err := createEntry(context.Background(), OSFS{}, step, &Plan{Force: tt.force})
+
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("createEntry() expected error but got none")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("createEntry() unexpected error: %v", err)
+				return
+			}
+
+			if result.Status != StatusCreated {
+				t.Errorf("createEntry() got unexpected result: %s", result.Status)
+			}
+
+			// Verify the file/directory was actually created
+			if _, err := os.Stat(step.Target); os.IsNotExist(err) {
+				t.Errorf("createEntry() did not create %s", step.Target)
+			}
+		})
+	}
+}
+
+func TestCreateEntryExistingFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	existingFile := filepath.Join(tmpDir, "existing.txt")
+
+	// Create an existing file
+	if err := os.WriteFile(existingFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create existing file: %v", err)
+	}
+
+	step := Step{Entry: Entry{Path: "existing.txt", Kind: KindFile}, Target: existingFile}
+
+	// Test without force - should skip
+	result, err := createEntry(context.Background(), OSFS{}, step, &Plan{})
+	if err != nil {
+		t.Errorf("createEntry() unexpected error: %v", err)
+	}
+	if result.Status != StatusSkipped {
+		t.Errorf("createEntry() expected %s, got %s", StatusSkipped, result.Status)
+	}
+
+	// Test with force - should overwrite
+	result, err = createEntry(context.Background(), OSFS{}, step, &Plan{Force: true})
+	if err != nil {
+		t.Errorf("createEntry() unexpected error: %v", err)
+	}
+	if result.Status != StatusOverwritten {
+		t.Errorf("createEntry() expected %s, got %s", StatusOverwritten, result.Status)
+	}
+}
+
+func TestApply(t *testing.T) {
+	tmpDir := t.TempDir()
+	tree, err := ParseLines([]string{
+		"myapp/",
+		"├─ src/",
+		"│  └─ main.go",
+		"└─ README.md",
+	}, Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+
+	plan := NewPlan(tree, tmpDir)
+	report, err := Apply(context.Background(), plan, OSFS{})
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if got := report.Count(StatusCreated); got != 3 {
+		t.Errorf("Apply() created %d entries, want 3", got)
+	}
+
+	// A second run skips the files it created the first time.
+	report, err = Apply(context.Background(), plan, OSFS{})
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if got := report.Count(StatusSkipped); got != 2 {
+		t.Errorf("Apply() skipped %d entries, want 2", got)
+	}
+}
+
+func TestApplyCanceled(t *testing.T) {
+	tree := &Tree{Root: "myapp", Entries: []Entry{{Path: "a.txt", Kind: KindFile}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Apply(ctx, NewPlan(tree, t.TempDir()), OSFS{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Apply() error = %v, want context.Canceled", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("Apply() reported %d results after cancel, want 0", len(report.Results))
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		status   Status
+		expected string
+	}{
+		{StatusCreated, "created"},
+		{StatusSkipped, "skipped"},
+		{Status(42), "Status(42)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.String(); got != tt.expected {
+			t.Errorf("Status(%d).String() = %q, want %q", int(tt.status), got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package treeforge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ArchiveFormat selects the container an ArchiveFS writes.
+type ArchiveFormat int
+
+const (
+	ArchiveTar ArchiveFormat = iota
+	ArchiveTarGz
+	ArchiveZip
+)
+
+// ArchiveFormatFromName picks a format from an output file name.
+func ArchiveFormatFromName(name string) (ArchiveFormat, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return ArchiveTarGz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return ArchiveTar, nil
+	case strings.HasSuffix(lower, ".zip"):
+		return ArchiveZip, nil
+	}
+	return 0, fmt.Errorf("unsupported archive type %q (want .tar, .tar.gz, .tgz or .zip)", name)
+}
+
+// ArchiveFS is a write-only FS that streams everything applied to it into
+// a tar or zip archive. Paths should be relative, e.g. from a plan built
+// with an empty parent, so that the archive is rooted at the tree root.
+// Call Close to flush the archive.
+type ArchiveFS struct {
+	seen    *MemFS
+	modTime time.Time
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	zw      *zip.Writer
+}
+
+func NewArchiveFS(w io.Writer, format ArchiveFormat) *ArchiveFS {
+	a := &ArchiveFS{seen: NewMemFS(), modTime: time.Now()}
+	switch format {
+	case ArchiveZip:
+		a.zw = zip.NewWriter(w)
+	case ArchiveTarGz:
+		a.gz = gzip.NewWriter(w)
+		a.tw = tar.NewWriter(a.gz)
+	default:
+		a.tw = tar.NewWriter(w)
+	}
+	return a
+}
+
+func (a *ArchiveFS) MkdirAll(path string, perm fs.FileMode) error {
+	path = filepath.Clean(path)
+	if isFSRoot(path) {
+		return nil
+	}
+	if info, err := a.seen.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: path, Err: errNotDir}
+	}
+	if err := a.MkdirAll(filepath.Dir(path), perm); err != nil {
+		return err
+	}
+	if err := a.writeHeader(path, fs.ModeDir|perm.Perm(), nil); err != nil {
+		return err
+	}
+	return a.seen.MkdirAll(path, perm)
+}
+
+func (a *ArchiveFS) Stat(path string) (fs.FileInfo, error) {
+	return a.seen.Stat(path)
+}
+
+func (a *ArchiveFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	path = filepath.Clean(path)
+	if _, err := a.seen.Stat(path); err == nil {
+		// Archives cannot replace a member, only append a second copy.
+		return &fs.PathError{Op: "open", Path: path, Err: fs.ErrExist}
+	}
+	if err := a.seen.WriteFile(path, nil, perm); err != nil {
+		return err
+	}
+	return a.writeHeader(path, perm.Perm(), data)
+}
+
+func (a *ArchiveFS) writeHeader(path string, mode fs.FileMode, data []byte) error {
+	name := filepath.ToSlash(path)
+	if mode.IsDir() {
+		name += "/"
+	}
+	if a.zw != nil {
+		return a.writeZip(name, mode, data)
+	}
+	return a.writeTar(name, mode, data)
+}
+
+func (a *ArchiveFS) writeTar(name string, mode fs.FileMode, data []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    int64(mode.Perm()),
+		ModTime: a.modTime,
+		Size:    int64(len(data)),
+	}
+	if mode.IsDir() {
+		hdr.Typeflag = tar.TypeDir
+	}
+	if err := a.tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := a.tw.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+func (a *ArchiveFS) writeZip(name string, mode fs.FileMode, data []byte) error {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: a.modTime}
+	hdr.SetMode(mode)
+	if mode.IsDir() {
+		hdr.Method = zip.Store
+	}
+	w, err := a.zw.CreateHeader(hdr)
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("writing %s: %w", name, err)
+	}
+	return nil
+}
+
+// Close finishes the archive. It does not close the underlying writer.
+func (a *ArchiveFS) Close() error {
+	if a.zw != nil {
+		return a.zw.Close()
+	}
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	if a.gz != nil {
+		return a.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package treeforge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func archiveTestTree() *Tree {
+	return &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile},
+			{Path: "docs/guide.md", Kind: KindFile},
+		},
+	}
+}
+
+var archiveTestNames = []string{"myapp/", "myapp/src/", "myapp/src/main.go", "myapp/docs/", "myapp/docs/guide.md"}
+
+func TestArchiveFormatFromName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ArchiveFormat
+		hasError bool
+	}{
+		{name: "out.tar", expected: ArchiveTar},
+		{name: "out.tar.gz", expected: ArchiveTarGz},
+		{name: "OUT.TGZ", expected: ArchiveTarGz},
+		{name: "out.zip", expected: ArchiveZip},
+		{name: "out.rar", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ArchiveFormatFromName(tt.name)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("ArchiveFormatFromName(%q) expected error", tt.name)
+				}
+				return
+			}
+			if err != nil || format != tt.expected {
+				t.Errorf("ArchiveFormatFromName(%q) = %v, %v; want %v", tt.name, format, err, tt.expected)
+			}
+		})
+	}
+}
+
+func applyToArchive(t *testing.T, format ArchiveFormat) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	afs := NewArchiveFS(&buf, format)
+	if _, err := Apply(context.Background(), NewPlan(archiveTestTree(), ""), afs); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if err := afs.Close(); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFSTarGz(t *testing.T) {
+	data := applyToArchive(t, ArchiveTarGz)
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("gzip.NewReader() unexpected error: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Typeflag == tar.TypeDir && hdr.Mode != 0755 {
+			t.Errorf("%s mode = %o, want 755", hdr.Name, hdr.Mode)
+		}
+	}
+
+	if !reflect.DeepEqual(names, archiveTestNames) {
+		t.Errorf("tar entries = %v, want %v", names, archiveTestNames)
+	}
+}
+
+func TestArchiveFSZip(t *testing.T) {
+	data := applyToArchive(t, ArchiveZip)
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("zip.NewReader() unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+
+	if !reflect.DeepEqual(names, archiveTestNames) {
+		t.Errorf("zip entries = %v, want %v", names, archiveTestNames)
+	}
+}
+
+func TestArchiveFSDuplicateFile(t *testing.T) {
+	afs := NewArchiveFS(io.Discard, ArchiveTar)
+	if err := afs.WriteFile("a.txt", nil, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := afs.WriteFile("a.txt", nil, 0644); err == nil {
+		t.Errorf("WriteFile() expected error for duplicate member")
+	}
+	if err := afs.MkdirAll("a.txt/sub", 0755); err == nil {
+		t.Errorf("MkdirAll() expected error through a file")
+	}
+}
@@ -0,0 +1,112 @@
+package treeforge
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// conditionPrefix starts a comment that makes a line, or the block of
+// lines up to a matching "# end", depend on Options.Vars.
+const conditionPrefix = "if:"
+
+// block is an open "# if:" comment block.
+type block struct {
+	cond  string
+	line  int
+	holds bool
+}
+
+// skipped is a directory left out by a condition, which takes the lines
+// indented below it along.
+type skipped struct {
+	level  int
+	reason string
+}
+
+// comment returns the text of the comment cut from line, without the '#'.
+func comment(line, cut string) string {
+	rest := strings.TrimLeft(line[len(cut):], " \t│|")
+	rest, _ = strings.CutPrefix(rest, "#")
+	return strings.TrimSpace(rest)
+}
+
+// condition returns the expression of an "if: expr" comment.
+func condition(comment string) (string, bool) {
+	rest, ok := strings.CutPrefix(comment, conditionPrefix)
+	if !ok {
+		return "", false
+	}
+	expr := strings.TrimSpace(rest)
+	return expr, expr != ""
+}
+
+// blockComment handles a line with no entry: "# if: expr" opens a block
+// and "# end" closes the innermost one. Other comments, and an "# end"
+// with no block open, are ignored as before conditions existed.
+func (p *parser) blockComment(num int, text string) {
+	if expr, ok := condition(text); ok {
+		holds := p.holds(num, "", expr)
+		p.blocks = append(p.blocks, block{cond: expr, line: num, holds: holds})
+		return
+	}
+	if text == "end" && len(p.blocks) > 0 {
+		p.blocks = p.blocks[:len(p.blocks)-1]
+	}
+}
+
+// exclusion returns why the entry on line num is left out, or "" if it
+// is kept. A directory left out takes its children with it.
+func (p *parser) exclusion(num int, path string, l parsedLine) string {
+	if p.skip != nil && l.level > p.skip.level {
+		return p.skip.reason
+	}
+	p.skip = nil
+
+	reason := ""
+	for _, b := range p.blocks {
+		if !b.holds {
+			reason = conditionPrefix + " " + b.cond
+			break
+		}
+	}
+	if expr, ok := condition(l.comment); ok && reason == "" && !p.holds(num, path, expr) {
+		reason = conditionPrefix + " " + expr
+	}
+	if reason != "" && l.isDir {
+		p.skip = &skipped{level: l.level, reason: reason}
+	}
+	return reason
+}
+
+// keep adds e to the entries, or to the excluded entries with reason.
+func (p *parser) keep(e Entry, reason string) {
+	if reason != "" {
+		p.excluded = append(p.excluded, Exclusion{Entry: e, Reason: reason})
+		return
+	}
+	p.entries = append(p.entries, e)
+}
+
+// holds evaluates expr as a text/template pipeline against Options.Vars,
+// as {{ if expr }} would. Unset variables are false. A condition that
+// cannot be evaluated is reported at its line and holds.
+func (p *parser) holds(num int, path, expr string) bool {
+	tmpl, err := template.New(conditionPrefix).Parse("{{ if " + expr + " }}1{{ end }}")
+	if err == nil {
+		var out strings.Builder
+		if err = tmpl.Execute(&out, p.opts.Vars); err == nil {
+			return out.String() == "1"
+		}
+	}
+	p.issues = append(p.issues, Issue{Path: path, Line: num, File: p.file, Message: fmt.Sprintf("condition %q: %v", expr, err)})
+	return true
+}
+
+// checkBlocks reports "# if:" blocks left open at the end of the input.
+func (p *parser) checkBlocks() {
+	for _, b := range p.blocks {
+		p.issues = append(p.issues, Issue{Path: "# if: " + b.cond, Line: b.line, File: p.file, Message: "no matching # end"})
+	}
+	p.blocks = nil
+}
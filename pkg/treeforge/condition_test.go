@@ -0,0 +1,193 @@
+package treeforge
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// serviceTree describes several variants of a service with conditions.
+var serviceTree = []string{
+	"svc/",
+	"├─ main.go",
+	"├─ Dockerfile      # if: .Docker",
+	"├─ k8s/            # if: .Kubernetes",
+	"│  ├─ deploy.yaml",
+	"│  └─ service.yaml",
+	"# if: eq .Proto \"grpc\"",
+	"├─ proto/",
+	"│  └─ svc.proto",
+	"# if: not .Docker",
+	"├─ run.sh",
+	"# end",
+	"# end",
+	"└─ README.md       # notes, not a condition",
+}
+
+func conditionPaths(entries []Entry) []string {
+	paths := []string{}
+	for _, e := range entries {
+		paths = append(paths, filepath.ToSlash(e.Path))
+	}
+	return paths
+}
+
+func TestParseConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		vars     map[string]any
+		want     []string
+		excluded map[string]string
+	}{
+		{
+			name: "no variables",
+			want: []string{"main.go", "README.md"},
+			excluded: map[string]string{
+				"Dockerfile":       "if: .Docker",
+				"k8s":              "if: .Kubernetes",
+				"k8s/deploy.yaml":  "if: .Kubernetes",
+				"k8s/service.yaml": "if: .Kubernetes",
+				"proto":            `if: eq .Proto "grpc"`,
+				"proto/svc.proto":  `if: eq .Proto "grpc"`,
+				"run.sh":           `if: eq .Proto "grpc"`,
+			},
+		},
+		{
+			name: "every variant",
+			vars: map[string]any{"Docker": true, "Kubernetes": true, "Proto": "grpc"},
+			want: []string{"main.go", "Dockerfile", "k8s", "k8s/deploy.yaml", "k8s/service.yaml", "proto", "proto/svc.proto", "README.md"},
+			excluded: map[string]string{
+				"run.sh": "if: not .Docker",
+			},
+		},
+		{
+			name: "false and strings",
+			vars: map[string]any{"Docker": false, "Kubernetes": "yes", "Proto": "rest"},
+			want: []string{"main.go", "k8s", "k8s/deploy.yaml", "k8s/service.yaml", "README.md"},
+			excluded: map[string]string{
+				"Dockerfile":      "if: .Docker",
+				"proto":           `if: eq .Proto "grpc"`,
+				"proto/svc.proto": `if: eq .Proto "grpc"`,
+				"run.sh":          `if: eq .Proto "grpc"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ParseLines(serviceTree, Options{Vars: tt.vars})
+			if err != nil {
+				t.Fatalf("ParseLines() unexpected error: %v", err)
+			}
+			if got := conditionPaths(tree.Entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+			excluded := map[string]string{}
+			for _, ex := range tree.Excluded {
+				excluded[filepath.ToSlash(ex.Entry.Path)] = ex.Reason
+			}
+			if !reflect.DeepEqual(excluded, tt.excluded) {
+				t.Errorf("excluded = %v, want %v", excluded, tt.excluded)
+			}
+		})
+	}
+}
+
+func TestParseConditionProblems(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{
+			name:  "bad expression",
+			lines: []string{"app/", "└─ k8s/  # if: (.Kubernetes"},
+			want:  `line 2: k8s: condition "(.Kubernetes"`,
+		},
+		{
+			name:  "if without end",
+			lines: []string{"app/", "│  # if: .Docker", "└─ Dockerfile"},
+			want:  "line 2: # if: .Docker: no matching # end",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLines(tt.lines, Options{})
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("ParseLines() error = %v, want a *ParseError", err)
+			}
+			if len(perr.Issues) != 1 || !strings.HasPrefix(perr.Issues[0].String(), tt.want) {
+				t.Errorf("issues = %v, want one starting %q", perr.Issues, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStrayEnd(t *testing.T) {
+	// A plain "# end" comment with no block open is just a comment.
+	tree, err := ParseLines([]string{"app/", "├─ main.go", "# end", "└─ README.md  # end"}, Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	if got, want := conditionPaths(tree.Entries), []string{"main.go", "README.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestParseConditionInclude(t *testing.T) {
+	files := map[string]string{
+		"docker.tree": "docker/\n├─ Dockerfile\n└─ compose.yaml  # if: .Compose\n",
+	}
+	lines := []string{"app/", "├─ @include docker.tree  # if: .Docker", "└─ main.go"}
+	for _, docker := range []bool{true, false} {
+		tree, err := ParseLines(lines, Options{Include: includeFiles(files), Vars: map[string]any{"Docker": docker}})
+		if err != nil {
+			t.Fatalf("ParseLines() unexpected error: %v", err)
+		}
+		want := []string{"main.go"}
+		excluded := []string{"docker", "docker/Dockerfile", "docker/compose.yaml"}
+		if docker {
+			want = []string{"docker", "docker/Dockerfile", "main.go"}
+			excluded = []string{"docker/compose.yaml"}
+		}
+		if got := conditionPaths(tree.Entries); !reflect.DeepEqual(got, want) {
+			t.Errorf("Docker=%v: entries = %v, want %v", docker, got, want)
+		}
+		var got []string
+		for _, ex := range tree.Excluded {
+			got = append(got, filepath.ToSlash(ex.Entry.Path))
+		}
+		if !reflect.DeepEqual(got, excluded) {
+			t.Errorf("Docker=%v: excluded = %v, want %v", docker, got, excluded)
+		}
+	}
+}
+
+func TestParserConditions(t *testing.T) {
+	ps := NewParser(strings.NewReader(strings.Join(serviceTree, "\n")), Options{Vars: map[string]any{"Kubernetes": true}})
+	var got []string
+	for {
+		e, err := ps.Next()
+		if err != nil {
+			break
+		}
+		got = append(got, filepath.ToSlash(e.Path))
+	}
+	want := []string{"main.go", "k8s", "k8s/deploy.yaml", "k8s/service.yaml", "README.md"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("streamed entries = %v, want %v", got, want)
+	}
+
+	ps = NewParser(strings.NewReader("app/\n# if: .Docker\n└─ Dockerfile\n"), Options{})
+	for {
+		if _, err := ps.Next(); err != nil {
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("Next() error = %v, want a *ParseError for the open block", err)
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package treeforge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Conflict says what Apply does with a file entry whose target already
+// exists.
+type Conflict int
+
+const (
+	// ConflictSkip leaves the existing file alone.
+	ConflictSkip Conflict = iota
+	// ConflictOverwrite replaces the existing file.
+	ConflictOverwrite
+	// ConflictBackup copies the existing file to name.bak.<timestamp>,
+	// then replaces it.
+	ConflictBackup
+	// ConflictRename leaves the existing file alone and writes the entry
+	// beside it under the first free numbered name, such as main.1.go.
+	ConflictRename
+	// ConflictAsk lets Plan.Ask decide for each file.
+	ConflictAsk
+	// ConflictMerge rewrites the existing file with both versions, the
+	// lines that differ wrapped in git-style conflict markers.
+	ConflictMerge
+)
+
+var conflictNames = []string{"skip", "overwrite", "backup", "rename", "ask", "merge"}
+
+func (c Conflict) String() string {
+	if c >= 0 && int(c) < len(conflictNames) {
+		return conflictNames[c]
+	}
+	return fmt.Sprintf("Conflict(%d)", int(c))
+}
+
+// ParseConflict returns the policy named s: skip, overwrite, backup,
+// rename, ask or merge.
+func ParseConflict(s string) (Conflict, error) {
+	for i, name := range conflictNames {
+		if s == name {
+			return Conflict(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown conflict policy %q (want %s)", s, strings.Join(conflictNames, ", "))
+}
+
+// Reader is implemented by filesystems that can read files back, which
+// content checks, backups, merges and Plan.Ask need.
+type Reader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+var errNoReader = errors.New("filesystem cannot read files back")
+
+// resolveConflict handles a file step whose target already exists. When
+// the entry carries content and the existing file can be read, files that
+// already match are left alone and empty ones are filled in; only real
+// differences are settled by the plan's policy. The existing file is read
+// only when there is content to compare or the policy needs it, and a
+// target that cannot be read, such as a directory, is skipped.
+func resolveConflict(fsys FS, step Step, plan *Plan) (Result, error) {
+	res := Result{Step: step, Status: StatusSkipped}
+	content := step.Entry.Content
+	var existing []byte
+	readable := false
+	if len(content) > 0 || plan.needsExisting() {
+		var err error
+		existing, err = readFile(fsys, step.Target)
+		switch {
+		case err == nil:
+			readable = true
+		case !errors.Is(err, errNoReader):
+			return res, nil
+		}
+	}
+
+	if readable && len(content) > 0 {
+		switch {
+		case bytes.Equal(existing, content):
+			res.Status = StatusUnchanged
+			return res, nil
+		case len(existing) == 0:
+			res.Status = StatusFilled
+			return res, writeFile(fsys, step.Target, content)
+		}
+	}
+
+	action, err := plan.decide(step, existing, readable)
+	if err != nil {
+		return res, err
+	}
+	return settle(fsys, res, action, existing, readable)
+}
+
+// settle carries out the policy's action for a file that differs.
+func settle(fsys FS, res Result, action Conflict, existing []byte, readable bool) (Result, error) {
+	var err error
+	step := res.Step
+	target, content := step.Target, step.Entry.Content
+	switch action {
+	case ConflictOverwrite:
+		res.Status = StatusOverwritten
+	case ConflictBackup:
+		res.Status = StatusBackedUp
+		res.Path, err = backupFile(fsys, step.Target)
+	case ConflictRename:
+		res.Status = StatusRenamed
+		res.Path, err = freeName(fsys, numberedName(step.Target))
+		target = res.Path
+	case ConflictMerge:
+		if !readable {
+			return res, fmt.Errorf("merging %s: %w", step.Target, errNoReader)
+		}
+		res.Status = StatusMerged
+		content = mergeMarkers(existing, content)
+	default:
+		if readable && len(content) > 0 {
+			res.Status = StatusDiffers
+		}
+		return res, nil
+	}
+	if err != nil {
+		return res, err
+	}
+	return res, writeFile(fsys, target, content)
+}
+
+// needsExisting reports whether the conflict policy works with the
+// contents of existing files.
+func (p *Plan) needsExisting() bool {
+	switch p.OnConflict {
+	case ConflictBackup, ConflictAsk, ConflictMerge:
+		return true
+	}
+	return false
+}
+
+// decide picks the action for one existing file.
+func (p *Plan) decide(step Step, existing []byte, readable bool) (Conflict, error) {
+	switch {
+	case p.OnConflict == ConflictSkip && p.Force:
+		return ConflictOverwrite, nil
+	case p.OnConflict != ConflictAsk:
+		return p.OnConflict, nil
+	case p.Ask == nil:
+		return ConflictSkip, nil
+	case !readable:
+		return 0, fmt.Errorf("reading %s: %w", step.Target, errNoReader)
+	}
+
+	p.askMu.Lock()
+	defer p.askMu.Unlock()
+	action, err := p.Ask(step, existing)
+	if action == ConflictAsk {
+		action = ConflictSkip
+	}
+	return action, err
+}
+
+func readFile(fsys FS, path string) ([]byte, error) {
+	r, ok := fsys.(Reader)
+	if !ok {
+		return nil, errNoReader
+	}
+	return r.ReadFile(path)
+}
+
+// backupFile copies the file at path to a timestamped name beside it and
+// returns that name.
+func backupFile(fsys FS, path string) (string, error) {
+	data, err := readFile(fsys, path)
+	if err != nil {
+		return "", err
+	}
+	stamp := time.Now().Format("20060102-150405")
+	backup, err := freeName(fsys, func(n int) string {
+		if n == 0 {
+			return path + ".bak." + stamp
+		}
+		return fmt.Sprintf("%s.bak.%s-%d", path, stamp, n)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := fsys.WriteFile(backup, data, 0644); err != nil {
+		return "", fmt.Errorf("backing up %s: %w", path, err)
+	}
+	return backup, nil
+}
+
+// numberedName returns the n-th alternative to path, keeping the
+// extension last: main.go becomes main.1.go, .env becomes .env.1.
+func numberedName(path string) func(int) string {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	if stem == "" {
+		stem, ext = name, ""
+	}
+	return func(n int) string {
+		return filepath.Join(dir, fmt.Sprintf("%s.%d%s", stem, n+1, ext))
+	}
+}
+
+// freeName returns the first of name(0), name(1), ... that does not exist.
+func freeName(fsys FS, name func(int) string) (string, error) {
+	for n := 0; n < 1000; n++ {
+		_, err := fsys.Stat(name(n))
+		if errors.Is(err, fs.ErrNotExist) {
+			return name(n), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no free name near %s", name(0))
+}
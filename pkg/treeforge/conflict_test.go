@@ -0,0 +1,239 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// conflictFS returns a MemFS where out/app/main.go already holds "old".
+func conflictFS(t *testing.T) *MemFS {
+	t.Helper()
+	fsys := NewMemFS()
+	if err := fsys.MkdirAll("out/app", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+	if err := fsys.WriteFile("out/app/main.go", []byte("old"), 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	return fsys
+}
+
+func conflictPlan() *Plan {
+	tree := &Tree{Root: "app", Entries: []Entry{{Path: "main.go", Kind: KindFile, Content: []byte("new")}}}
+	return NewPlan(tree, "out")
+}
+
+func TestApplyOnConflict(t *testing.T) {
+	tests := []struct {
+		policy   Conflict
+		status   Status
+		contents string // of main.go afterwards
+		path     string // Result.Path prefix
+		other    string // contents of Result.Path
+	}{
+		{policy: ConflictSkip, status: StatusDiffers, contents: "old"},
+		{policy: ConflictOverwrite, status: StatusOverwritten, contents: "new"},
+		{policy: ConflictBackup, status: StatusBackedUp, contents: "new", path: "out/app/main.go.bak.", other: "old"},
+		{policy: ConflictRename, status: StatusRenamed, contents: "old", path: "out/app/main.1.go", other: "new"},
+		{policy: ConflictAsk, status: StatusDiffers, contents: "old"}, // no Ask func
+		{policy: ConflictMerge, status: StatusMerged, contents: "<<<<<<< existing\nold\n||||||| base\n=======\nnew\n>>>>>>> tree\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			fsys := conflictFS(t)
+			plan := conflictPlan()
+			plan.OnConflict = tt.policy
+
+			report, err := Apply(context.Background(), plan, fsys)
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			res := report.Results[0]
+			if res.Status != tt.status {
+				t.Errorf("Status = %s, want %s", res.Status, tt.status)
+			}
+			if data, _ := fsys.ReadFile("out/app/main.go"); string(data) != tt.contents {
+				t.Errorf("main.go = %q, want %q", data, tt.contents)
+			}
+			if !strings.HasPrefix(res.Path, tt.path) || (tt.path == "") != (res.Path == "") {
+				t.Errorf("Path = %q, want prefix %q", res.Path, tt.path)
+			}
+			if tt.other != "" {
+				if data, _ := fsys.ReadFile(res.Path); string(data) != tt.other {
+					t.Errorf("%s = %q, want %q", res.Path, data, tt.other)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyOnConflictAsk(t *testing.T) {
+	fsys := conflictFS(t)
+	if err := fsys.WriteFile("out/app/main.1.go", nil, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	plan := conflictPlan()
+	plan.OnConflict = ConflictAsk
+	var asked []string
+	plan.Ask = func(step Step, existing []byte) (Conflict, error) {
+		asked = append(asked, step.Entry.Path+"="+string(existing))
+		return ConflictRename, nil
+	}
+
+	report, err := Apply(context.Background(), plan, fsys)
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if len(asked) != 1 || asked[0] != "main.go=old" {
+		t.Errorf("Ask called with %v, want [main.go=old]", asked)
+	}
+	if res := report.Results[0]; res.Status != StatusRenamed || res.Path != "out/app/main.2.go" {
+		t.Errorf("Result = %s %q, want renamed to the first free name out/app/main.2.go", res.Status, res.Path)
+	}
+
+	stop := errors.New("stop")
+	plan.Ask = func(Step, []byte) (Conflict, error) { return ConflictSkip, stop }
+	if _, err := Apply(context.Background(), plan, fsys); !errors.Is(err, stop) {
+		t.Errorf("Apply() error = %v, want the error from Ask", err)
+	}
+}
+
+func TestApplyExistingContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		content  string
+		status   Status
+		after    string
+	}{
+		{name: "identical", existing: "same\n", content: "same\n", status: StatusUnchanged, after: "same\n"},
+		{name: "empty file is filled", existing: "", content: "new\n", status: StatusFilled, after: "new\n"},
+		{name: "different", existing: "old\n", content: "new\n", status: StatusDiffers, after: "old\n"},
+		{name: "no content in the tree", existing: "old\n", content: "", status: StatusSkipped, after: "old\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := conflictFS(t)
+			if err := fsys.WriteFile("out/app/main.go", []byte(tt.existing), 0644); err != nil {
+				t.Fatalf("WriteFile() unexpected error: %v", err)
+			}
+			plan := conflictPlan()
+			plan.Steps[0].Entry.Content = []byte(tt.content)
+			plan.OnConflict = ConflictAsk
+			plan.Ask = func(Step, []byte) (Conflict, error) {
+				return ConflictSkip, nil
+			}
+
+			report, err := Apply(context.Background(), plan, fsys)
+			if err != nil {
+				t.Fatalf("Apply() unexpected error: %v", err)
+			}
+			if got := report.Results[0].Status; got != tt.status {
+				t.Errorf("Status = %s, want %s", got, tt.status)
+			}
+			if data, _ := fsys.ReadFile("out/app/main.go"); string(data) != tt.after {
+				t.Errorf("main.go = %q, want %q", data, tt.after)
+			}
+		})
+	}
+}
+
+func TestApplyDirectoryAtFileTarget(t *testing.T) {
+	for _, policy := range []Conflict{ConflictSkip, ConflictBackup, ConflictAsk, ConflictMerge} {
+		for _, dryRun := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s dry-run=%v", policy, dryRun), func(t *testing.T) {
+				parent := t.TempDir()
+				if err := os.MkdirAll(filepath.Join(parent, "app", "main.go"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				plan := NewPlan(&Tree{Root: "app", Entries: []Entry{
+					{Path: "main.go", Kind: KindFile, Content: []byte("new")},
+					{Path: "README.md", Kind: KindFile},
+				}}, parent)
+				plan.OnConflict = policy
+				plan.Ask = func(Step, []byte) (Conflict, error) { return ConflictOverwrite, nil }
+
+				var fsys FS = OSFS{}
+				if dryRun {
+					fsys = NewDryRunFS(fsys)
+				}
+				report, err := Apply(context.Background(), plan, fsys)
+				if err != nil {
+					t.Fatalf("Apply() unexpected error: %v", err)
+				}
+				if got := report.Results[0].Status; got != StatusSkipped {
+					t.Errorf("main.go Status = %s, want %s", got, StatusSkipped)
+				}
+				if got := report.Results[1].Status; got != StatusCreated {
+					t.Errorf("README.md Status = %s, want %s", got, StatusCreated)
+				}
+			})
+		}
+	}
+}
+
+// countingFS counts the files read back from a MemFS.
+type countingFS struct {
+	*MemFS
+	reads int
+}
+
+func (c *countingFS) ReadFile(path string) ([]byte, error) {
+	c.reads++
+	return c.MemFS.ReadFile(path)
+}
+
+func TestApplySkipDoesNotRead(t *testing.T) {
+	fsys := &countingFS{MemFS: conflictFS(t)}
+	plan := conflictPlan()
+	plan.Steps[0].Entry.Content = nil
+	if _, err := Apply(context.Background(), plan, fsys); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if fsys.reads != 0 {
+		t.Errorf("Apply() read %d existing files for an entry with no content under skip, want 0", fsys.reads)
+	}
+}
+
+func TestMergeMarkers(t *testing.T) {
+	existing := "package main\n\nfunc main() {}\n// local\n"
+	tree := "package main\n\nfunc main() {\n}\n"
+	want := "package main\n\n" +
+		"<<<<<<< existing\nfunc main() {}\n// local\n||||||| base\n=======\nfunc main() {\n}\n>>>>>>> tree\n"
+	if got := string(mergeMarkers([]byte(existing), []byte(tree))); got != want {
+		t.Errorf("mergeMarkers() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseConflict(t *testing.T) {
+	for _, name := range []string{"skip", "overwrite", "backup", "rename", "ask", "merge"} {
+		c, err := ParseConflict(name)
+		if err != nil || c.String() != name {
+			t.Errorf("ParseConflict(%q) = %v, %v", name, c, err)
+		}
+	}
+	if _, err := ParseConflict("clobber"); err == nil {
+		t.Errorf("ParseConflict(clobber) expected error")
+	}
+}
+
+func TestNumberedName(t *testing.T) {
+	tests := map[string]string{
+		"dir/main.go":      "dir/main.1.go",
+		"dir/.env":         "dir/.env.1",
+		"Makefile":         "Makefile.1",
+		"a/archive.tar.gz": "a/archive.tar.1.gz",
+	}
+	for in, want := range tests {
+		if got := numberedName(in)(0); got != want {
+			t.Errorf("numberedName(%q)(0) = %q, want %q", in, got, want)
+		}
+	}
+}
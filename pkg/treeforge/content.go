@@ -0,0 +1,85 @@
+package treeforge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"text/template"
+)
+
+// TemplateSuffix marks a content file that is rendered with text/template
+// before use: main.go.tmpl provides the content of main.go.
+const TemplateSuffix = ".tmpl"
+
+// FillContent sets the content of file entries from files in src, looking
+// each entry up at its path under dir. A file with the entry's exact name
+// is used as is; failing that, one with TemplateSuffix added is rendered
+// with vars as its data. Entries that already have content, or have no
+// regular file in src, are left alone. src must implement Reader. It
+// returns the number of entries filled.
+func (t *Tree) FillContent(src FS, dir string, vars map[string]any) (int, error) {
+	filled := 0
+	for i := range t.Entries {
+		ok, err := ReadContent(&t.Entries[i], src, dir, vars)
+		if err != nil {
+			return filled, err
+		}
+		if ok {
+			filled++
+		}
+	}
+	return filled, nil
+}
+
+// ReadContent fills in e the way FillContent does and reports whether it
+// did.
+func ReadContent(e *Entry, src FS, dir string, vars map[string]any) (bool, error) {
+	if e.Kind != KindFile || len(e.Content) > 0 {
+		return false, nil
+	}
+	path := filepath.Join(dir, e.Path)
+	data, ok, err := readRegular(src, path)
+	if err != nil || ok {
+		e.Content = data
+		return ok, err
+	}
+
+	data, ok, err = readRegular(src, path+TemplateSuffix)
+	if err != nil || !ok {
+		return false, err
+	}
+	if e.Content, err = render(path+TemplateSuffix, data, vars); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// readRegular reads the file at path, reporting false if there is no
+// regular file there.
+func readRegular(src FS, path string) ([]byte, bool, error) {
+	info, err := src.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	data, err := readFile(src, path)
+	return data, err == nil, err
+}
+
+// render executes a content template. Referring to a variable that is
+// not set is an error rather than an empty string.
+func render(name string, text []byte, vars map[string]any) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("parsing template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, vars); err != nil {
+		return nil, fmt.Errorf("rendering template: %w", err)
+	}
+	return out.Bytes(), nil
+}
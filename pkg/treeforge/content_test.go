@@ -0,0 +1,92 @@
+package treeforge
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFillContent(t *testing.T) {
+	src := NewMemFS()
+	for path, data := range map[string]string{
+		"tpl/main.go":        "package main\n",
+		"tpl/cmd/run.go":     "package cmd\n",
+		"tpl/docs/README.md": "docs\n",
+		"tpl/go.mod.tmpl":    "module {{ .Module }}\n",
+		"tpl/LICENSE":        "MIT\n",
+		"tpl/LICENSE.tmpl":   "{{ .Unused }}",
+		"other/extra.txt":    "unused\n",
+	} {
+		if err := src.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree := &Tree{Entries: []Entry{
+		{Path: "cmd", Kind: KindDir},
+		{Path: "cmd/run.go", Kind: KindFile},
+		{Path: "main.go", Kind: KindFile, Content: []byte("kept\n")},
+		{Path: "docs", Kind: KindFile},
+		{Path: "missing.go", Kind: KindFile},
+		{Path: "go.mod", Kind: KindFile},
+		{Path: "LICENSE", Kind: KindFile},
+	}}
+	filled, err := tree.FillContent(src, "tpl", map[string]any{"Module": "example.com/app"})
+	if err != nil {
+		t.Fatalf("FillContent() unexpected error: %v", err)
+	}
+	if filled != 3 {
+		t.Errorf("FillContent() = %d, want 3", filled)
+	}
+
+	want := map[string]string{
+		"cmd/run.go": "package cmd\n",
+		"main.go":    "kept\n",
+		"go.mod":     "module example.com/app\n",
+		"LICENSE":    "MIT\n",
+	}
+	got := map[string]string{}
+	for _, e := range tree.Entries {
+		if e.Content != nil {
+			got[e.Path] = string(e.Content)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("contents = %v, want %v", got, want)
+	}
+}
+
+func TestFillContentNeedsReader(t *testing.T) {
+	tree := &Tree{Entries: []Entry{{Path: "main.go", Kind: KindFile}}}
+	src := NewArchiveFS(io.Discard, ArchiveTar)
+	if err := src.WriteFile("main.go", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.FillContent(src, "", nil); err == nil {
+		t.Errorf("FillContent() from a write-only FS expected error")
+	}
+}
+
+func TestFillContentTemplateErrors(t *testing.T) {
+	for name, text := range map[string]string{
+		"missing variable": "{{ .Missing }}",
+		"syntax":           "{{ .Name ",
+	} {
+		t.Run(name, func(t *testing.T) {
+			src := NewMemFS()
+			if err := src.WriteFile("main.go.tmpl", []byte(text), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tree := &Tree{Entries: []Entry{{Path: "main.go", Kind: KindFile}}}
+			_, err := tree.FillContent(src, "", map[string]any{"Name": "x"})
+			if err == nil || !strings.Contains(err.Error(), "main.go.tmpl") {
+				t.Errorf("FillContent() = %v, want an error naming main.go.tmpl", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package treeforge
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Dedupe cleans up entries that name the same path more than once.
+// Repeated directories are merged silently. Repeated files are dropped
+// and reported as warnings. A path listed as both a file and a directory,
+// or a file with entries below it, is a conflict: conflicts are returned
+// separately and the tree should not be applied while any remain.
+func (t *Tree) Dedupe() (warnings, conflicts []Issue) {
+	first := map[string]Entry{}
+	kept := make([]Entry, 0, len(t.Entries))
+
+	for _, e := range t.Entries {
+		prev, seen := first[e.Path]
+		switch {
+		case !seen:
+			first[e.Path] = e
+			kept = append(kept, e)
+		case prev.Kind != e.Kind:
+			conflicts = append(conflicts, Issue{Path: e.Path, Line: e.Line, File: e.File,
+				Message: fmt.Sprintf("listed as a %s here but as a %s on %s", e.Kind, prev.Kind, position(prev.File, prev.Line))})
+		case e.Kind == KindFile:
+			warnings = append(warnings, Issue{Path: e.Path, Line: e.Line, File: e.File,
+				Message: fmt.Sprintf("duplicate of the file on %s; ignored", position(prev.File, prev.Line))})
+		}
+	}
+	t.Entries = kept
+
+	return warnings, append(conflicts, fileParentConflicts(kept, first)...)
+}
+
+// fileParentConflicts finds files that other entries treat as a directory.
+func fileParentConflicts(entries []Entry, byPath map[string]Entry) []Issue {
+	var conflicts []Issue
+	reported := map[string]bool{}
+	for _, e := range entries {
+		for dir := filepath.Dir(e.Path); dir != "." && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+			parent, ok := byPath[dir]
+			if !ok || parent.Kind != KindFile || reported[dir] {
+				continue
+			}
+			reported[dir] = true
+			conflicts = append(conflicts, Issue{Path: parent.Path, Line: parent.Line, File: parent.File,
+				Message: fmt.Sprintf("is a file but %s (%s) is listed inside it", e.Path, position(e.File, e.Line))})
+		}
+	}
+	return conflicts
+}
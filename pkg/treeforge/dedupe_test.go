@@ -0,0 +1,89 @@
+package treeforge
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		expected  []Entry
+		warnings  []string
+		conflicts []string
+	}{
+		{
+			name: "duplicate directories are merged",
+			lines: []string{
+				"myapp/",
+				"├─ src/",
+				"│  └─ a.go",
+				"├─ src/",
+				"│  └─ b.go",
+			},
+			expected: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/a.go", Kind: KindFile},
+				{Path: "src/b.go", Kind: KindFile},
+			},
+		},
+		{
+			name: "duplicate files are reported with both lines",
+			lines: []string{
+				"myapp/",
+				"├─ main.go",
+				"└─ main.go",
+			},
+			expected: []Entry{{Path: "main.go", Kind: KindFile}},
+			warnings: []string{"line 3: main.go: duplicate of the file on line 2; ignored"},
+		},
+		{
+			name: "file and directory with the same name",
+			lines: []string{
+				"myapp/",
+				"├─ config",
+				"└─ config/",
+				"   └─ app.yaml",
+			},
+			expected: []Entry{
+				{Path: "config", Kind: KindFile},
+				{Path: "config/app.yaml", Kind: KindFile},
+			},
+			conflicts: []string{
+				"line 3: config: listed as a dir here but as a file on line 2",
+				"line 2: config: is a file but config/app.yaml (line 4) is listed inside it",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ParseLines(tt.lines, Options{})
+			if err != nil {
+				t.Fatalf("ParseLines() unexpected error: %v", err)
+			}
+
+			warnings, conflicts := tree.Dedupe()
+
+			if got := withoutLines(tree.Entries); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Dedupe() entries mismatch:\n%s", cmpEntries(tt.expected, got))
+			}
+			if got := issueStrings(warnings); !reflect.DeepEqual(got, tt.warnings) {
+				t.Errorf("Dedupe() warnings = %q, want %q", got, tt.warnings)
+			}
+			if got := issueStrings(conflicts); !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("Dedupe() conflicts = %q, want %q", got, tt.conflicts)
+			}
+		})
+	}
+}
+
+func issueStrings(issues []Issue) []string {
+	var out []string
+	for _, issue := range issues {
+		out = append(out, strings.ReplaceAll(issue.String(), "\\", "/"))
+	}
+	return out
+}
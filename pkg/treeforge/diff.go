@@ -0,0 +1,107 @@
+package treeforge
+
+import "strings"
+
+// maxDiffCells bounds the work DiffLines does; larger inputs are treated
+// as entirely different.
+const maxDiffCells = 4_000_000
+
+// DiffLines compares old and new line by line. Each returned line is
+// prefixed with " " if both have it, "-" if only old does, or "+" if
+// only new does.
+func DiffLines(old, new []byte) []string {
+	a, b := splitLines(old), splitLines(new)
+	if len(a)*len(b) > maxDiffCells {
+		out := make([]string, 0, len(a)+len(b))
+		for _, l := range a {
+			out = append(out, "-"+l)
+		}
+		for _, l := range b {
+			out = append(out, "+"+l)
+		}
+		return out
+	}
+	return lcsDiff(a, b)
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// lcsDiff merges a and b into one list along their longest common
+// subsequence.
+func lcsDiff(a, b []string) []string {
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, " "+a[i])
+			i, j = i+1, j+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "-"+a[i])
+			i++
+		default:
+			out = append(out, "+"+b[j])
+			j++
+		}
+	}
+	return out
+}
+
+// mergeMarkers combines the existing contents of a file with the
+// contents from the tree, keeping the lines they share and wrapping each
+// difference in diff3-style conflict markers for the user to resolve.
+// There is no version both descend from, so the base section between
+// "|||||||" and "=======" is always empty.
+func mergeMarkers(existing, tree []byte) []byte {
+	var b strings.Builder
+	var ours, theirs []string
+	flush := func() {
+		if len(ours)+len(theirs) == 0 {
+			return
+		}
+		b.WriteString("<<<<<<< existing\n")
+		for _, l := range ours {
+			b.WriteString(l + "\n")
+		}
+		b.WriteString("||||||| base\n=======\n")
+		for _, l := range theirs {
+			b.WriteString(l + "\n")
+		}
+		b.WriteString(">>>>>>> tree\n")
+		ours, theirs = nil, nil
+	}
+
+	for _, l := range DiffLines(existing, tree) {
+		switch l[0] {
+		case '-':
+			ours = append(ours, l[1:])
+		case '+':
+			theirs = append(theirs, l[1:])
+		default:
+			flush()
+			b.WriteString(l[1:] + "\n")
+		}
+	}
+	flush()
+	return []byte(b.String())
+}
@@ -0,0 +1,270 @@
+package treeforge
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FS is the filesystem a Plan is applied to.
+type FS interface {
+	MkdirAll(path string, perm fs.FileMode) error
+	Stat(path string) (fs.FileInfo, error)
+	WriteFile(path string, data []byte, perm fs.FileMode) error
+}
+
+// Remover is implemented by filesystems that can delete what they
+// created, which JournalFS.Rollback needs.
+type Remover interface {
+	// Remove deletes a file or an empty directory.
+	Remove(path string) error
+}
+
+// OSFS applies changes to the local filesystem.
+type OSFS struct{}
+
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (OSFS) Stat(path string) (fs.FileInfo, error)        { return os.Stat(path) }
+func (OSFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(path, data, perm)
+}
+func (OSFS) Remove(path string) error             { return os.Remove(path) }
+func (OSFS) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
+
+// MemFS is an in-memory FS. The zero value is not usable; call NewMemFS.
+type MemFS struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	mode fs.FileMode
+	data []byte
+}
+
+func NewMemFS() *MemFS {
+	return &MemFS{nodes: map[string]*memNode{}}
+}
+
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.mkdirAll(filepath.Clean(path), perm)
+}
+
+func (m *MemFS) mkdirAll(path string, perm fs.FileMode) error {
+	if isFSRoot(path) {
+		return nil
+	}
+	if n, ok := m.nodes[path]; ok {
+		if n.mode.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: path, Err: errNotDir}
+	}
+	if err := m.mkdirAll(filepath.Dir(path), perm); err != nil {
+		return err
+	}
+	m.nodes[path] = &memNode{mode: fs.ModeDir | perm.Perm()}
+	return nil
+}
+
+func (m *MemFS) Stat(path string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	if isFSRoot(path) {
+		return memInfo{name: path, mode: fs.ModeDir | 0755}, nil
+	}
+	n, ok := m.nodes[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+	return memInfo{name: filepath.Base(path), mode: n.mode, size: int64(len(n.data))}, nil
+}
+
+func (m *MemFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+
+	if dir := filepath.Dir(path); !isFSRoot(dir) {
+		if parent, ok := m.nodes[dir]; !ok || !parent.mode.IsDir() {
+			return &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+		}
+	}
+	if n, ok := m.nodes[path]; ok && n.mode.IsDir() {
+		return &fs.PathError{Op: "open", Path: path, Err: errIsDir}
+	}
+	m.nodes[path] = &memNode{mode: perm.Perm(), data: append([]byte(nil), data...)}
+	return nil
+}
+
+// Remove deletes a file or an empty directory.
+func (m *MemFS) Remove(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	n, ok := m.nodes[path]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	if n.mode.IsDir() {
+		prefix := path + string(filepath.Separator)
+		for p := range m.nodes {
+			if strings.HasPrefix(p, prefix) {
+				return &fs.PathError{Op: "remove", Path: path, Err: errNotEmpty}
+			}
+		}
+	}
+	delete(m.nodes, path)
+	return nil
+}
+
+// ReadFile returns a copy of the contents of the file at path.
+func (m *MemFS) ReadFile(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	n, ok := m.nodes[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	if n.mode.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: path, Err: errIsDir}
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// Paths returns every directory and file in the filesystem, sorted.
+func (m *MemFS) Paths() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make([]string, 0, len(m.nodes))
+	for path := range m.nodes {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// put stores n at path without checking its parents.
+func (m *MemFS) put(path string, n *memNode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[path] = n
+}
+
+var (
+	errNotDir   = errors.New("not a directory")
+	errIsDir    = errors.New("is a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+func isFSRoot(path string) bool {
+	return path == "." || path == filepath.Dir(path)
+}
+
+type memInfo struct {
+	name string
+	mode fs.FileMode
+	size int64
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// OpKind identifies a change recorded by a DryRunFS.
+type OpKind int
+
+const (
+	OpMkdir OpKind = iota
+	OpWrite
+)
+
+// Op is a single change a DryRunFS was asked to make.
+type Op struct {
+	Kind OpKind
+	Path string
+	Data []byte
+	Perm fs.FileMode
+}
+
+// DryRunFS records changes instead of making them. Reads see the
+// underlying filesystem with the recorded changes layered on top, so an
+// Apply against it makes the same decisions a real one would.
+type DryRunFS struct {
+	base    FS
+	overlay *MemFS
+
+	mu  sync.Mutex
+	ops []Op
+}
+
+// NewDryRunFS returns a DryRunFS that reads from base and never writes to it.
+func NewDryRunFS(base FS) *DryRunFS {
+	return &DryRunFS{base: base, overlay: NewMemFS()}
+}
+
+func (d *DryRunFS) MkdirAll(path string, perm fs.FileMode) error {
+	if info, err := d.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil
+		}
+		return &fs.PathError{Op: "mkdir", Path: path, Err: errNotDir}
+	}
+	path = filepath.Clean(path)
+	d.record(Op{Kind: OpMkdir, Path: path, Perm: perm})
+	// The overlay only tracks planned paths; their parents may live in base.
+	d.overlay.put(path, &memNode{mode: fs.ModeDir | perm.Perm()})
+	return nil
+}
+
+func (d *DryRunFS) Stat(path string) (fs.FileInfo, error) {
+	if info, err := d.overlay.Stat(path); err == nil {
+		return info, nil
+	}
+	return d.base.Stat(path)
+}
+
+func (d *DryRunFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if info, err := d.Stat(path); err == nil && info.IsDir() {
+		return &fs.PathError{Op: "open", Path: path, Err: errIsDir}
+	}
+	path = filepath.Clean(path)
+	data = append([]byte(nil), data...)
+	d.record(Op{Kind: OpWrite, Path: path, Data: data, Perm: perm})
+	d.overlay.put(path, &memNode{mode: perm.Perm(), data: data})
+	return nil
+}
+
+// ReadFile returns the recorded contents of a planned file, or reads the
+// file from base if base is a Reader.
+func (d *DryRunFS) ReadFile(path string) ([]byte, error) {
+	if data, err := d.overlay.ReadFile(path); err == nil {
+		return data, nil
+	}
+	return readFile(d.base, path)
+}
+
+func (d *DryRunFS) record(op Op) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ops = append(d.ops, op)
+}
+
+// Ops returns the changes recorded so far, in order.
+func (d *DryRunFS) Ops() []Op {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Op(nil), d.ops...)
+}
@@ -0,0 +1,135 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMemFS(t *testing.T) {
+	m := NewMemFS()
+
+	if err := m.MkdirAll("/root/a/b", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+	if err := m.WriteFile("/root/a/b/file.txt", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	info, err := m.Stat("/root/a/b/file.txt")
+	if err != nil {
+		t.Fatalf("Stat() unexpected error: %v", err)
+	}
+	if info.IsDir() || info.Size() != 5 || info.Name() != "file.txt" {
+		t.Errorf("Stat() = %s dir=%v size=%d, want file.txt dir=false size=5", info.Name(), info.IsDir(), info.Size())
+	}
+
+	data, err := m.ReadFile("/root/a/b/file.txt")
+	if err != nil || string(data) != "hello" {
+		t.Errorf("ReadFile() = %q, %v; want %q", data, err, "hello")
+	}
+
+	want := []string{"/root", "/root/a", "/root/a/b", "/root/a/b/file.txt"}
+	if got := m.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+}
+
+func TestMemFSErrors(t *testing.T) {
+	m := NewMemFS()
+	if err := m.MkdirAll("dir", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+	if err := m.WriteFile("dir/file", nil, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"write without parent", m.WriteFile("missing/file", nil, 0644)},
+		{"write over directory", m.WriteFile("dir", nil, 0644)},
+		{"mkdir through file", m.MkdirAll("dir/file/sub", 0755)},
+		{"read directory", func() error { _, err := m.ReadFile("dir"); return err }()},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expected error but got none", tt.name)
+		}
+	}
+
+	if _, err := m.Stat("nope"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() error = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestApplyMemFS(t *testing.T) {
+	tree := &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile},
+			{Path: "docs/guide.md", Kind: KindFile},
+		},
+	}
+	m := NewMemFS()
+
+	if _, err := Apply(context.Background(), NewPlan(tree, "/work"), m); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	want := []string{"/work", "/work/myapp", "/work/myapp/docs", "/work/myapp/docs/guide.md", "/work/myapp/src", "/work/myapp/src/main.go"}
+	if got := m.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+}
+
+func TestDryRunFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	existing := filepath.Join(tmpDir, "myapp", "README.md")
+	if err := os.MkdirAll(filepath.Dir(existing), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(existing, []byte("keep"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	tree := &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile},
+			{Path: "README.md", Kind: KindFile},
+		},
+	}
+	dry := NewDryRunFS(OSFS{})
+
+	report, err := Apply(context.Background(), NewPlan(tree, tmpDir), dry)
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+	if got := report.Count(StatusSkipped); got != 1 {
+		t.Errorf("Apply() skipped %d entries, want 1", got)
+	}
+
+	wantOps := []Op{
+		{Kind: OpMkdir, Path: filepath.Join(tmpDir, "myapp", "src"), Perm: 0755},
+		{Kind: OpWrite, Path: filepath.Join(tmpDir, "myapp", "src", "main.go"), Perm: 0644},
+	}
+	if got := dry.Ops(); !reflect.DeepEqual(got, wantOps) {
+		t.Errorf("Ops() = %+v, want %+v", got, wantOps)
+	}
+
+	// Nothing may have been written to disk
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp", "src")); !os.IsNotExist(err) {
+		t.Errorf("DryRunFS created %s", filepath.Join(tmpDir, "myapp", "src"))
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "keep" {
+		t.Errorf("DryRunFS modified %s", existing)
+	}
+}
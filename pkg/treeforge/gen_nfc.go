@@ -0,0 +1,190 @@
+//go:build ignore
+
+// gen_nfc writes nfc_table.go from the Unicode character database:
+// UnicodeData.txt and CompositionExclusions.txt, fetched from unicode.org
+// or read from a local directory with -ucd.
+//
+//	go run gen_nfc.go -version 14.0.0
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	version = flag.String("version", "14.0.0", "Unicode version to generate tables for")
+	ucd     = flag.String("ucd", "", "directory holding the UCD files; fetched from unicode.org if empty")
+	output  = flag.String("o", "nfc_table.go", "file to write")
+)
+
+func main() {
+	flag.Parse()
+	log.SetFlags(0)
+
+	ccc, decomps, excluded := loadUCD()
+	composites, others := splitDecompositions(ccc, decomps, excluded)
+	var marks []rune
+	for r := range ccc {
+		marks = append(marks, r)
+	}
+	slices.Sort(marks)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by gen_nfc.go from the Unicode %s character database; DO NOT EDIT.\n\n", *version)
+	buf.WriteString("package treeforge\n\n")
+	buf.WriteString("// nfcPairs maps a starter followed by a combining character to their\n")
+	buf.WriteString("// canonical composite, excluding composition exclusions and Hangul\n")
+	buf.WriteString("// syllables (which are composed algorithmically).\n")
+	buf.WriteString("var nfcPairs = map[[2]rune]rune{\n")
+	writeRows(&buf, len(composites), 3, func(i int) string {
+		r := composites[i]
+		return fmt.Sprintf("{0x%04X, 0x%04X}: 0x%04X,", decomps[r][0], decomps[r][1], r)
+	})
+	buf.WriteString("}\n\n")
+	buf.WriteString("// nfcDecomposeOnly maps the characters whose canonical decomposition\n")
+	buf.WriteString("// is never composed back: singletons, composition exclusions and\n")
+	buf.WriteString("// non-starter decompositions.\n")
+	buf.WriteString("var nfcDecomposeOnly = map[rune][]rune{\n")
+	writeRows(&buf, len(others), 3, func(i int) string {
+		d := make([]string, len(decomps[others[i]]))
+		for j, r := range decomps[others[i]] {
+			d[j] = fmt.Sprintf("0x%04X", r)
+		}
+		return fmt.Sprintf("0x%04X: {%s},", others[i], strings.Join(d, ", "))
+	})
+	buf.WriteString("}\n\n")
+	buf.WriteString("// combiningClasses holds the nonzero canonical combining classes, which\n")
+	buf.WriteString("// put combining marks in canonical order.\n")
+	buf.WriteString("var combiningClasses = map[rune]uint8{\n")
+	writeRows(&buf, len(marks), 6, func(i int) string {
+		return fmt.Sprintf("0x%04X: %d,", marks[i], ccc[marks[i]])
+	})
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitDecompositions sorts the canonical decompositions into primary
+// composites, the pairs that compose back, and all others: singletons,
+// non-starter decompositions and listed exclusions.
+func splitDecompositions(ccc map[rune]uint8, decomps map[rune][]rune, excluded map[rune]bool) (composites, others []rune) {
+	for r, d := range decomps {
+		if len(d) == 2 && !excluded[r] && ccc[r] == 0 && ccc[d[0]] == 0 {
+			composites = append(composites, r)
+		} else {
+			others = append(others, r)
+		}
+	}
+	slices.Sort(composites)
+	slices.Sort(others)
+	return composites, others
+}
+
+// loadUCD returns the nonzero combining classes, the canonical
+// decompositions and the listed composition exclusions.
+func loadUCD() (ccc map[rune]uint8, decomps map[rune][]rune, excluded map[rune]bool) {
+	ccc, decomps, excluded = map[rune]uint8{}, map[rune][]rune{}, map[rune]bool{}
+	readUCD("UnicodeData.txt", func(fields []string) {
+		r := parseRune(fields[0])
+		class, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			log.Fatalf("UnicodeData.txt: %s: bad combining class %q", fields[0], fields[3])
+		}
+		if class != 0 {
+			ccc[r] = uint8(class)
+		}
+		if d := fields[5]; d != "" && !strings.HasPrefix(d, "<") {
+			for _, f := range strings.Fields(d) {
+				decomps[r] = append(decomps[r], parseRune(f))
+			}
+		}
+	})
+	readUCD("CompositionExclusions.txt", func(fields []string) {
+		first, last, _ := strings.Cut(fields[0], "..")
+		if last == "" {
+			last = first
+		}
+		for r := parseRune(first); r <= parseRune(last); r++ {
+			excluded[r] = true
+		}
+	})
+	return ccc, decomps, excluded
+}
+
+// writeRows writes n items, perRow to a line.
+func writeRows(w io.Writer, n, perRow int, item func(int) string) {
+	for i := 0; i < n; i += perRow {
+		row := make([]string, 0, perRow)
+		for j := i; j < n && j < i+perRow; j++ {
+			row = append(row, item(j))
+		}
+		fmt.Fprintf(w, "\t%s\n", strings.Join(row, " "))
+	}
+}
+
+// readUCD calls line with the semicolon-separated fields of each data
+// line of the named UCD file, without comments.
+func readUCD(name string, line func(fields []string)) {
+	r := openUCD(name)
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		line(fields)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalf("%s: %v", name, err)
+	}
+}
+
+func openUCD(name string) io.ReadCloser {
+	if *ucd != "" {
+		f, err := os.Open(filepath.Join(*ucd, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		return f
+	}
+	url := fmt.Sprintf("https://www.unicode.org/Public/%s/ucd/%s", *version, name)
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("%s: %s", url, resp.Status)
+	}
+	return resp.Body
+}
+
+func parseRune(s string) rune {
+	r, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		log.Fatalf("bad code point %q", s)
+	}
+	return rune(r)
+}
@@ -0,0 +1,38 @@
+package treeforge
+
+import "path/filepath"
+
+// GitkeepName is the placeholder file used to keep empty directories in git.
+const GitkeepName = ".gitkeep"
+
+// AddGitkeep adds a .gitkeep file to every directory the tree leaves
+// empty, including the root when the tree has no entries at all. Each
+// placeholder is inserted right after its directory. It returns the
+// number of files added.
+func (t *Tree) AddGitkeep() int {
+	if len(t.Entries) == 0 {
+		t.Entries = []Entry{{Path: GitkeepName, Kind: KindFile}}
+		return 1
+	}
+
+	// Every ancestor of an entry has something in it, even when the
+	// intermediate directories have no entry of their own.
+	nonEmpty := map[string]bool{}
+	for _, e := range t.Entries {
+		for dir := filepath.Dir(e.Path); dir != "." && !nonEmpty[dir]; dir = filepath.Dir(dir) {
+			nonEmpty[dir] = true
+		}
+	}
+
+	entries := make([]Entry, 0, len(t.Entries))
+	added := 0
+	for _, e := range t.Entries {
+		entries = append(entries, e)
+		if e.Kind == KindDir && !nonEmpty[e.Path] {
+			entries = append(entries, Entry{Path: filepath.Join(e.Path, GitkeepName), Kind: KindFile})
+			added++
+		}
+	}
+	t.Entries = entries
+	return added
+}
@@ -0,0 +1,67 @@
+package treeforge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddGitkeep(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []Entry
+		expected []Entry
+		added    int
+	}{
+		{
+			name:     "empty tree",
+			entries:  nil,
+			expected: []Entry{{Path: ".gitkeep", Kind: KindFile}},
+			added:    1,
+		},
+		{
+			name: "empty and non-empty directories",
+			entries: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/main.go", Kind: KindFile},
+				{Path: "logs", Kind: KindDir},
+				{Path: "data", Kind: KindDir},
+				{Path: "data/raw", Kind: KindDir},
+			},
+			expected: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/main.go", Kind: KindFile},
+				{Path: "logs", Kind: KindDir},
+				{Path: "logs/.gitkeep", Kind: KindFile},
+				{Path: "data", Kind: KindDir},
+				{Path: "data/raw", Kind: KindDir},
+				{Path: "data/raw/.gitkeep", Kind: KindFile},
+			},
+			added: 2,
+		},
+		{
+			name: "file under directory without its own entry",
+			entries: []Entry{
+				{Path: "a", Kind: KindDir},
+				{Path: "a/b/c.txt", Kind: KindFile},
+			},
+			expected: []Entry{
+				{Path: "a", Kind: KindDir},
+				{Path: "a/b/c.txt", Kind: KindFile},
+			},
+			added: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := &Tree{Root: "myapp", Entries: tt.entries}
+			added := tree.AddGitkeep()
+			if added != tt.added {
+				t.Errorf("AddGitkeep() = %d, want %d", added, tt.added)
+			}
+			if !reflect.DeepEqual(tree.Entries, tt.expected) {
+				t.Errorf("AddGitkeep() mismatch:\n%s", cmpEntries(tt.expected, tree.Entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package treeforge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	GitignoreName       = ".gitignore"
+	TreeforgeignoreName = ".treeforgeignore"
+)
+
+// Ignore matches paths against gitignore-style patterns. Later patterns
+// take precedence over earlier ones, and nothing inside an ignored
+// directory can be re-included, as in git.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	re      *regexp.Regexp
+	base    string // directory the pattern is relative to, slash-separated
+	negate  bool
+	dirOnly bool
+	source  string // where the pattern came from, for reporting
+}
+
+// Add parses patterns in gitignore syntax. base is the directory, relative
+// to the tree root, that anchored patterns are relative to ("" for the
+// root); source names where the patterns came from.
+func (ig *Ignore) Add(base, source string, patterns []string) error {
+	base = strings.Trim(filepath.ToSlash(base), "/")
+	if base == "." {
+		base = ""
+	}
+	for i, p := range patterns {
+		rule, ok, err := parseIgnorePattern(p)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", source, i+1, err)
+		}
+		if !ok {
+			continue
+		}
+		rule.base = base
+		rule.source = fmt.Sprintf("%s:%d: %s", source, i+1, strings.TrimSpace(p))
+		ig.rules = append(ig.rules, rule)
+	}
+	return nil
+}
+
+// AddFile adds the patterns in the file at name, anchored at base.
+// A missing file is not an error.
+func (ig *Ignore) AddFile(name, base string) error {
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return ig.Add(base, name, lines)
+}
+
+// LoadIgnoreDir collects .treeforgeignore from dir and every .gitignore
+// in dir and below it, skipping .git and directories already ignored.
+// A dir that does not exist yields an empty Ignore.
+func LoadIgnoreDir(dir string) (*Ignore, error) {
+	ig := &Ignore{}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return ig, nil
+	}
+	if err := ig.AddFile(filepath.Join(dir, TreeforgeignoreName), ""); err != nil {
+		return nil, err
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		if d.Name() == ".git" || (rel != "." && ig.Match(rel, true)) {
+			return filepath.SkipDir
+		}
+		return ig.AddFile(filepath.Join(p, GitignoreName), rel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ig, nil
+}
+
+// Match reports whether the path, relative to the tree root, is ignored.
+func (ig *Ignore) Match(name string, isDir bool) bool {
+	_, ok := ig.MatchRule(name, isDir)
+	return ok
+}
+
+// MatchRule is like Match but also describes the pattern responsible.
+func (ig *Ignore) MatchRule(name string, isDir bool) (string, bool) {
+	if ig == nil || len(ig.rules) == 0 {
+		return "", false
+	}
+	name = strings.Trim(filepath.ToSlash(name), "/")
+
+	// An ignored ancestor hides everything below it.
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		if rule, ok := ig.matchOne(strings.Join(parts[:i], "/"), true); ok {
+			return rule, true
+		}
+	}
+	return ig.matchOne(name, isDir)
+}
+
+func (ig *Ignore) matchOne(name string, isDir bool) (string, bool) {
+	for i := len(ig.rules) - 1; i >= 0; i-- {
+		r := ig.rules[i]
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, ok := relativeTo(name, r.base)
+		if !ok || !r.re.MatchString(rel) {
+			continue
+		}
+		if r.negate {
+			return "", false
+		}
+		return r.source, true
+	}
+	return "", false
+}
+
+func relativeTo(name, base string) (string, bool) {
+	if base == "" {
+		return name, true
+	}
+	if strings.HasPrefix(name, base+"/") {
+		return name[len(base)+1:], true
+	}
+	return "", false
+}
+
+// parseIgnorePattern turns one gitignore line into a rule. ok is false
+// for blank lines and comments.
+func parseIgnorePattern(line string) (rule ignoreRule, ok bool, err error) {
+	p := trimIgnoreTrailingSpace(line)
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(p, "!") {
+		rule.negate = true
+		p = p[1:]
+	} else if strings.HasPrefix(p, `\!`) || strings.HasPrefix(p, `\#`) {
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		rule.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return rule, false, nil
+	}
+
+	// A slash anywhere but the end anchors the pattern to its directory;
+	// otherwise it matches a name at any depth.
+	prefix := "^(?:.*/)?"
+	if strings.Contains(p, "/") {
+		prefix = "^"
+		p = strings.TrimPrefix(p, "/")
+	}
+
+	rule.re, err = regexp.Compile(prefix + globToRegexp(p) + "$")
+	if err != nil {
+		return rule, false, fmt.Errorf("invalid pattern %q", line)
+	}
+	return rule, true, nil
+}
+
+// trimIgnoreTrailingSpace drops trailing spaces unless escaped with "\".
+func trimIgnoreTrailingSpace(s string) string {
+	s = strings.TrimRight(s, "\r")
+	for strings.HasSuffix(s, " ") && !strings.HasSuffix(s, `\ `) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// globToRegexp translates gitignore glob syntax, including "**", into a
+// regular expression body.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			i = writeCharClass(&b, glob, i)
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// writeCharClass copies a [...] class starting at glob[i] and returns the
+// index of its closing bracket. An unterminated "[" is taken literally.
+func writeCharClass(b *strings.Builder, glob string, i int) int {
+	end := strings.IndexByte(glob[i+1:], ']')
+	if end < 0 {
+		b.WriteString(`\[`)
+		return i
+	}
+	class := glob[i+1 : i+1+end]
+	if strings.HasPrefix(class, "!") {
+		class = "^" + class[1:]
+	}
+	b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+	return i + 1 + end
+}
+
+// Exclude moves every entry matched by ig from t.Entries to t.Excluded
+// and returns how many were moved.
+func (t *Tree) Exclude(ig *Ignore) int {
+	kept := t.Entries[:0]
+	moved := 0
+	for _, e := range t.Entries {
+		if rule, ok := ig.MatchRule(e.Path, e.Kind == KindDir); ok {
+			t.Excluded = append(t.Excluded, Exclusion{Entry: e, Reason: rule})
+			moved++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	t.Entries = kept
+	return moved
+}
@@ -0,0 +1,170 @@
+package treeforge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	ig := &Ignore{}
+	err := ig.Add("", "test", []string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"node_modules/",
+		"/build",
+		"docs/**/draft.md",
+		"tmp/**",
+		"**/cache",
+		"file[0-9].txt",
+		"file[!0-9].bin",
+		`\#hash`,
+		"trailing   ",
+	})
+	if err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: "app.log", expected: true},
+		{path: "src/deep/app.log", expected: true},
+		{path: "keep.log", expected: false},
+		{path: "node_modules", isDir: true, expected: true},
+		{path: "node_modules", isDir: false, expected: false},
+		{path: "web/node_modules/x.js", expected: true},
+		{path: "build", isDir: true, expected: true},
+		{path: "src/build", isDir: true, expected: false},
+		{path: "docs/draft.md", expected: true},
+		{path: "docs/a/b/draft.md", expected: true},
+		{path: "other/draft.md", expected: false},
+		{path: "tmp/x/y", expected: true},
+		{path: "a/b/cache", isDir: true, expected: true},
+		{path: "file1.txt", expected: true},
+		{path: "filex.txt", expected: false},
+		{path: "filex.bin", expected: true},
+		{path: "file1.bin", expected: false},
+		{path: "#hash", expected: true},
+		{path: "trailing", expected: true},
+		{path: "src/main.go", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ig.Match(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIgnoreAnchoredBase(t *testing.T) {
+	ig := &Ignore{}
+	if err := ig.Add("sub", "sub/.gitignore", []string{"/only-here", "anywhere"}); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "sub/only-here", expected: true},
+		{path: "sub/x/only-here", expected: false},
+		{path: "only-here", expected: false},
+		{path: "sub/x/anywhere", expected: true},
+		{path: "anywhere", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := ig.Match(tt.path, false); got != tt.expected {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+}
+
+func TestLoadIgnoreDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":           "*.log\nvendor/\n",
+		".treeforgeignore":     "secrets/\n",
+		"sub/.gitignore":       "!debug.log\nlocal.txt\n",
+		"vendor/.gitignore":    "!*\n",
+		".git/info/.gitignore": "everything\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	ig, err := LoadIgnoreDir(dir)
+	if err != nil {
+		t.Fatalf("LoadIgnoreDir() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{path: "app.log", expected: true},
+		{path: "sub/debug.log", expected: false},
+		{path: "sub/local.txt", expected: true},
+		{path: "local.txt", expected: false},
+		{path: "secrets", isDir: true, expected: true},
+		{path: "vendor/lib.go", expected: true},
+		{path: "everything", expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := ig.Match(tt.path, tt.isDir); got != tt.expected {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.expected)
+		}
+	}
+
+	missing, err := LoadIgnoreDir(filepath.Join(dir, "missing"))
+	if err != nil || missing.Match("app.log", false) {
+		t.Errorf("LoadIgnoreDir() on missing dir = %v, %v; want empty Ignore", missing, err)
+	}
+}
+
+func TestTreeExclude(t *testing.T) {
+	ig := &Ignore{}
+	if err := ig.Add("", "exclude", []string{"node_modules/"}); err != nil {
+		t.Fatalf("Add() unexpected error: %v", err)
+	}
+
+	tree := &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "node_modules", Kind: KindDir},
+			{Path: "node_modules/x.js", Kind: KindFile},
+			{Path: "main.go", Kind: KindFile},
+		},
+	}
+
+	if got := tree.Exclude(ig); got != 2 {
+		t.Errorf("Exclude() = %d, want 2", got)
+	}
+	if want := []Entry{{Path: "main.go", Kind: KindFile}}; !reflect.DeepEqual(tree.Entries, want) {
+		t.Errorf("Exclude() entries mismatch:\n%s", cmpEntries(want, tree.Entries))
+	}
+	if len(tree.Excluded) != 2 || tree.Excluded[0].Reason != "exclude:1: node_modules/" {
+		t.Errorf("Exclude() excluded = %+v", tree.Excluded)
+	}
+
+	plan := NewPlan(tree, "/p")
+	if len(plan.Excluded) != 2 || plan.Excluded[1].Step.Target != filepath.Join("/p", "myapp", "node_modules", "x.js") {
+		t.Errorf("NewPlan() excluded = %+v", plan.Excluded)
+	}
+}
@@ -0,0 +1,100 @@
+package treeforge
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// includeDirective starts a line that splices in another tree file.
+const includeDirective = "@include "
+
+// includePath returns the fragment path of an "@include path" line.
+func includePath(l parsedLine) (string, bool) {
+	rest, ok := strings.CutPrefix(l.name, includeDirective)
+	if !ok {
+		return "", false
+	}
+	path := strings.TrimSpace(rest)
+	return path, path != ""
+}
+
+// include splices the entries of the fragment named on line num under the
+// directory open at level, or excludes them all for reason. Problems
+// inside the fragment are reported at their own lines in it; a fragment
+// that cannot be read at all is reported at the @include line.
+func (p *parser) include(num, level int, name, reason string) {
+	parent := p.levelParent[level]
+	entries, excluded, err := p.parseFragment(name)
+	if err != nil {
+		p.issues = append(p.issues, Issue{Path: name, Line: num, File: p.file, Message: err.Error()})
+		return
+	}
+	for _, e := range entries {
+		e.Path = filepath.Join(parent, e.Path)
+		p.keep(e, reason)
+	}
+	for _, ex := range excluded {
+		ex.Entry.Path = filepath.Join(parent, ex.Entry.Path)
+		p.excluded = append(p.excluded, ex)
+	}
+
+	// What follows continues the including file, at the @include's level.
+	p.maxLevel, p.lastFile = level, ""
+	for k := range p.levelParent {
+		if k > level {
+			delete(p.levelParent, k)
+		}
+	}
+}
+
+// parseFragment reads and parses the fragment name, relative to the file
+// being parsed. A fragment with a single root line keeps the root as a
+// directory entry, so that "ci/" with its children becomes ci/ under the
+// including directory. Entries its conditions left out are returned
+// separately.
+func (p *parser) parseFragment(name string) ([]Entry, []Exclusion, error) {
+	if p.opts.Include == nil {
+		return nil, nil, errors.New("@include is not supported for this input")
+	}
+	path := filepath.Join(filepath.Dir(p.file), filepath.FromSlash(name))
+	if filepath.IsAbs(name) {
+		path = filepath.Clean(name)
+	}
+	chain := append(slices.Clone(p.chain), path)
+	if slices.Contains(p.chain, path) {
+		return nil, nil, fmt.Errorf("include cycle: %s", strings.Join(chain, " → "))
+	}
+
+	lines, err := p.opts.Include(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot include %s: %w", path, err)
+	}
+	sub := newParser(p.opts)
+	sub.file, sub.chain = path, chain
+	entries, roots, err := sub.parseLines(lines)
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		p.issues = append(p.issues, perr.Issues...)
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if len(roots) != 1 || filepath.Clean(roots[0]) == "." {
+		return entries, sub.excluded, nil
+	}
+	root := Entry{Path: roots[0], Kind: KindDir, Line: sub.roots[0].line, File: path}
+	wrapped := append(make([]Entry, 0, len(entries)+1), root)
+	for _, e := range entries {
+		e.Path = filepath.Join(root.Path, e.Path)
+		wrapped = append(wrapped, e)
+	}
+	for i := range sub.excluded {
+		sub.excluded[i].Entry.Path = filepath.Join(root.Path, sub.excluded[i].Entry.Path)
+	}
+	return wrapped, sub.excluded, nil
+}
@@ -0,0 +1,135 @@
+package treeforge
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// includeFiles serves fragments from a map, keyed by slash paths.
+func includeFiles(files map[string]string) func(string) ([]string, error) {
+	return func(path string) ([]string, error) {
+		data, ok := files[filepath.ToSlash(path)]
+		if !ok {
+			return nil, fs.ErrNotExist
+		}
+		return strings.Split(strings.TrimSuffix(data, "\n"), "\n"), nil
+	}
+}
+
+func TestParseInclude(t *testing.T) {
+	files := map[string]string{
+		"fragments/ci.tree":         "ci/\n├─ lint.yml\n└─ @include steps/test.tree\n",
+		"fragments/steps/test.tree": "├─ test.yml\n└─ cover.yml\n",
+		"fragments/docs.tree":       "./\n├─ index.md\n└─ guide.md\n",
+	}
+	lines := []string{
+		"app/",
+		"├─ @include fragments/ci.tree",
+		"├─ docs/",
+		"│  └─ @include fragments/docs.tree",
+		"└─ main.go",
+	}
+	tree, err := ParseLines(lines, Options{Include: includeFiles(files)})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+
+	ci, steps, docs := filepath.FromSlash("fragments/ci.tree"), filepath.FromSlash("fragments/steps/test.tree"), filepath.FromSlash("fragments/docs.tree")
+	want := []Entry{
+		{Path: "ci", Kind: KindDir, Line: 1, File: ci},
+		{Path: filepath.FromSlash("ci/lint.yml"), Kind: KindFile, Line: 2, File: ci},
+		{Path: filepath.FromSlash("ci/test.yml"), Kind: KindFile, Line: 1, File: steps},
+		{Path: filepath.FromSlash("ci/cover.yml"), Kind: KindFile, Line: 2, File: steps},
+		{Path: "docs", Kind: KindDir, Line: 3},
+		{Path: filepath.FromSlash("docs/index.md"), Kind: KindFile, Line: 2, File: docs},
+		{Path: filepath.FromSlash("docs/guide.md"), Kind: KindFile, Line: 3, File: docs},
+		{Path: "main.go", Kind: KindFile, Line: 5},
+	}
+	if tree.Root != "app" || !reflect.DeepEqual(tree.Entries, want) {
+		t.Errorf("ParseLines() = %q %+v\nwant %q %+v", tree.Root, tree.Entries, "app", want)
+	}
+}
+
+func TestParseIncludeProblems(t *testing.T) {
+	files := map[string]string{
+		"a.tree":      "├─ x.go\n└─ @include b.tree\n",
+		"b.tree":      "└─ @include a.tree\n",
+		"strict.tree": "├─ ok.go\n│     └─ deep.go\n",
+	}
+	tests := []struct {
+		name  string
+		lines []string
+		opts  Options
+		want  string
+	}{
+		{
+			name:  "cycle",
+			lines: []string{"app/", "└─ @include a.tree"},
+			want:  "line 1 of b.tree: a.tree: include cycle: a.tree → b.tree → a.tree",
+		},
+		{
+			name:  "missing fragment",
+			lines: []string{"app/", "├─ src/", "│  └─ @include none.tree"},
+			want:  "line 3: none.tree: cannot include none.tree",
+		},
+		{
+			name:  "strict problem inside a fragment",
+			lines: []string{"app/", "└─ @include strict.tree"},
+			opts:  Options{Strict: true},
+			want:  "line 2 of strict.tree: deep.go: indented to level 2",
+		},
+		{
+			name:  "no include function",
+			lines: []string{"app/", "└─ @include a.tree"},
+			want:  "line 2: a.tree: @include is not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name != "no include function" {
+				tt.opts.Include = includeFiles(files)
+			}
+			_, err := ParseLines(tt.lines, tt.opts)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("ParseLines() error = %v, want a *ParseError", err)
+			}
+			if len(perr.Issues) != 1 || !strings.HasPrefix(perr.Issues[0].String(), tt.want) {
+				t.Errorf("issues = %v, want one starting %q", perr.Issues, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIncludeFirstLine(t *testing.T) {
+	files := map[string]string{"base.tree": "app/\n└─ main.go\n"}
+	tree, err := ParseLines([]string{"@include base.tree", "README.md"}, Options{Include: includeFiles(files)})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	want := []Entry{
+		{Path: "app", Kind: KindDir, Line: 1, File: "base.tree"},
+		{Path: filepath.FromSlash("app/main.go"), Kind: KindFile, Line: 2, File: "base.tree"},
+		{Path: "README.md", Kind: KindFile, Line: 2},
+	}
+	if tree.Root != "." || !reflect.DeepEqual(tree.Entries, want) {
+		t.Errorf("ParseLines() = %q %+v\nwant %q %+v", tree.Root, tree.Entries, ".", want)
+	}
+}
+
+func TestDedupeAcrossInclude(t *testing.T) {
+	files := map[string]string{"ci.tree": "├─ main.go\n"}
+	tree, err := ParseLines([]string{"app/", "├─ main.go", "└─ @include ci.tree"}, Options{Include: includeFiles(files)})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	warnings, _ := tree.Dedupe()
+	want := "line 1 of ci.tree: main.go: duplicate of the file on line 2; ignored"
+	if len(warnings) != 1 || warnings[0].String() != want {
+		t.Errorf("Dedupe() warnings = %v, want %q", warnings, want)
+	}
+}
@@ -0,0 +1,103 @@
+package treeforge
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// JournalFS wraps an FS and remembers every directory and file it creates
+// that did not exist before, so an interrupted or failed run can be undone
+// with Rollback. Files that already existed and were overwritten are not
+// restored.
+type JournalFS struct {
+	base FS
+
+	mu      sync.Mutex
+	created []string
+}
+
+// NewJournalFS returns a JournalFS writing through to base.
+func NewJournalFS(base FS) *JournalFS {
+	return &JournalFS{base: base}
+}
+
+func (j *JournalFS) MkdirAll(path string, perm fs.FileMode) error {
+	// Collect the missing directories, innermost first.
+	var missing []string
+	for p := filepath.Clean(path); !isFSRoot(p); p = filepath.Dir(p) {
+		if _, err := j.base.Stat(p); err == nil {
+			break
+		}
+		missing = append(missing, p)
+	}
+
+	if err := j.base.MkdirAll(path, perm); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		j.record(missing[i])
+	}
+	return nil
+}
+
+func (j *JournalFS) Stat(path string) (fs.FileInfo, error) {
+	return j.base.Stat(path)
+}
+
+// ReadFile reads through to base, which must be a Reader.
+func (j *JournalFS) ReadFile(path string) ([]byte, error) {
+	return readFile(j.base, path)
+}
+
+func (j *JournalFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	_, statErr := j.base.Stat(path)
+	if err := j.base.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	if statErr != nil {
+		j.record(filepath.Clean(path))
+	}
+	return nil
+}
+
+func (j *JournalFS) record(path string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.created = append(j.created, path)
+}
+
+// Created returns the paths created so far, in the order they were made.
+func (j *JournalFS) Created() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]string(nil), j.created...)
+}
+
+// Rollback removes everything created so far, newest first, and returns
+// how many paths it removed. A directory that has since gained other
+// files is left in place and reported.
+func (j *JournalFS) Rollback() (int, error) {
+	r, ok := j.base.(Remover)
+	if !ok {
+		return 0, fmt.Errorf("rollback: %T cannot remove files", j.base)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	removed := 0
+	var errs []error
+	for i := len(j.created) - 1; i >= 0; i-- {
+		err := r.Remove(j.created[i])
+		switch {
+		case err == nil:
+			removed++
+		case !errors.Is(err, fs.ErrNotExist):
+			errs = append(errs, err)
+		}
+	}
+	j.created = nil
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestJournalFSRollback(t *testing.T) {
+	base := NewMemFS()
+	if err := base.MkdirAll("out/app/src", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+	if err := base.WriteFile("out/app/README.md", []byte("keep"), 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	before := base.Paths()
+
+	tree := &Tree{Root: "app", Entries: []Entry{
+		{Path: "src", Kind: KindDir},
+		{Path: "src/main.go", Kind: KindFile},
+		{Path: "internal/db/db.go", Kind: KindFile},
+		{Path: "README.md", Kind: KindFile},
+	}}
+	jfs := NewJournalFS(base)
+	if _, err := Apply(context.Background(), NewPlan(tree, "out"), jfs); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	want := []string{"out/app/src/main.go", "out/app/internal", "out/app/internal/db", "out/app/internal/db/db.go"}
+	if got := jfs.Created(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Created() = %v, want %v", got, want)
+	}
+
+	removed, err := jfs.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback() unexpected error: %v", err)
+	}
+	if removed != len(want) {
+		t.Errorf("Rollback() removed %d paths, want %d", removed, len(want))
+	}
+	if got := base.Paths(); !reflect.DeepEqual(got, before) {
+		t.Errorf("after Rollback() paths = %v, want %v", got, before)
+	}
+}
+
+func TestJournalFSRollbackKeepsForeignFiles(t *testing.T) {
+	base := NewMemFS()
+	jfs := NewJournalFS(base)
+	if err := jfs.MkdirAll("out/new", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+	// Something else writes into the directory behind the journal's back.
+	if err := base.WriteFile("out/new/other.txt", nil, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	if _, err := jfs.Rollback(); err == nil {
+		t.Errorf("Rollback() expected an error for a non-empty directory")
+	}
+	if _, err := base.Stat("out/new/other.txt"); err != nil {
+		t.Errorf("Rollback() removed a file it did not create")
+	}
+}
+
+func TestJournalFSRollbackUnsupported(t *testing.T) {
+	jfs := NewJournalFS(NewDryRunFS(NewMemFS()))
+	if _, err := jfs.Rollback(); err == nil {
+		t.Errorf("Rollback() expected an error for an FS without Remove")
+	}
+}
+
+func TestMemFSRemove(t *testing.T) {
+	m := NewMemFS()
+	if err := m.WriteFile("a.txt", nil, 0644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+	if err := m.MkdirAll("d/e", 0755); err != nil {
+		t.Fatalf("MkdirAll() unexpected error: %v", err)
+	}
+
+	if err := m.Remove("d"); !errors.Is(err, errNotEmpty) {
+		t.Errorf("Remove(non-empty dir) = %v, want errNotEmpty", err)
+	}
+	for _, p := range []string{"a.txt", "d/e", "d"} {
+		if err := m.Remove(p); err != nil {
+			t.Errorf("Remove(%q) unexpected error: %v", p, err)
+		}
+	}
+	if got := m.Paths(); len(got) != 0 {
+		t.Errorf("Paths() = %v after removing everything", got)
+	}
+}
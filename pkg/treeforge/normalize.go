@@ -0,0 +1,270 @@
+package treeforge
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+//go:generate go run gen_nfc.go -version 14.0.0
+
+// Normalization selects the clean-ups applied to each line before it is
+// parsed. Diagrams copied from chat UIs, PDFs and word processors carry
+// characters that look right but make for surprising file names.
+type Normalization uint
+
+const (
+	// NormalizeNFC composes base letters and combining marks into their
+	// precomposed form, so "e" + U+0301 becomes "é" as macOS and most
+	// editors expect.
+	NormalizeNFC Normalization = 1 << iota
+	// NormalizeSpaces turns no-break, ideographic and other Unicode
+	// spaces into plain spaces.
+	NormalizeSpaces
+	// NormalizeInvisible strips zero-width characters, byte order marks,
+	// soft hyphens and bidirectional controls.
+	NormalizeInvisible
+	// NormalizeWidth folds full-width ASCII such as "ｍａｉｎ．ｇｏ" to ASCII,
+	// except for the slashes, which would split the name.
+	NormalizeWidth
+	// NormalizeQuotes turns curly quotes and primes into ASCII quotes.
+	NormalizeQuotes
+
+	NormalizeNone Normalization = 0
+	NormalizeAll                = NormalizeNFC | NormalizeSpaces | NormalizeInvisible | NormalizeWidth | NormalizeQuotes
+)
+
+var normalizationNames = []struct {
+	name string
+	n    Normalization
+}{
+	{"nfc", NormalizeNFC},
+	{"spaces", NormalizeSpaces},
+	{"invisible", NormalizeInvisible},
+	{"width", NormalizeWidth},
+	{"quotes", NormalizeQuotes},
+}
+
+// ParseNormalization parses "all", "none", or a comma-separated list of
+// nfc, spaces, invisible, width and quotes.
+func ParseNormalization(s string) (Normalization, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "all":
+		return NormalizeAll, nil
+	case "none", "":
+		return NormalizeNone, nil
+	}
+
+	var n Normalization
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		found := false
+		for _, nn := range normalizationNames {
+			if nn.name == part {
+				n |= nn.n
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown normalization %q (want all, none, or a list of nfc, spaces, invisible, width, quotes)", part)
+		}
+	}
+	return n, nil
+}
+
+func (n Normalization) String() string {
+	switch n {
+	case NormalizeAll:
+		return "all"
+	case NormalizeNone:
+		return "none"
+	}
+	var names []string
+	for _, nn := range normalizationNames {
+		if n&nn.n != 0 {
+			names = append(names, nn.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// normalize applies n to s. The returned offsets map each byte offset of
+// the result that starts a rune, plus len(result), to the offset in s the
+// rune came from, so a span of the result can be traced back to the input.
+func (n Normalization) normalize(s string) (string, map[int]int) {
+	runes := make([]sourceRune, 0, len(s))
+	for i, r := range s {
+		if n&NormalizeInvisible != 0 && isInvisible(r) {
+			continue
+		}
+		runes = append(runes, sourceRune{r: n.mapRune(r), at: i})
+	}
+	if n&NormalizeNFC != 0 {
+		runes = nfc(runes)
+	}
+
+	out := make([]byte, 0, len(s))
+	offsets := make(map[int]int, len(runes)+1)
+	for _, sr := range runes {
+		offsets[len(out)] = sr.at
+		out = utf8.AppendRune(out, sr.r)
+	}
+	offsets[len(out)] = len(s)
+	return string(out), offsets
+}
+
+// sourceRune is a rune of normalized text and the offset in the input it
+// came from.
+type sourceRune struct {
+	r  rune
+	at int
+}
+
+func (n Normalization) mapRune(r rune) rune {
+	switch {
+	case n&NormalizeSpaces != 0 && isOddSpace(r):
+		return ' '
+	// Full-width slashes stay as written rather than becoming path
+	// separators.
+	case n&NormalizeWidth != 0 && r >= '\uFF01' && r <= '\uFF5E' && !isSeparator(r-'\uFF01'+'!'):
+		return r - '\uFF01' + '!'
+	case n&NormalizeQuotes != 0:
+		switch r {
+		case '\u2018', '\u2019', '\u201A', '\u201B', '\u2032':
+			return '\''
+		case '\u201C', '\u201D', '\u201E', '\u201F', '\u2033':
+			return '"'
+		}
+	}
+	return r
+}
+
+func isSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func isOddSpace(r rune) bool {
+	switch r {
+	case '\u00A0', '\u1680', '\u202F', '\u205F', '\u3000':
+		return true
+	}
+	return r >= '\u2000' && r <= '\u200A'
+}
+
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u00AD', '\u180E', '\u2060', '\uFEFF':
+		return true
+	}
+	return r >= '\u200B' && r <= '\u200F' || // zero-width space and joiners, LRM, RLM
+		r >= '\u202A' && r <= '\u202E' || r >= '\u2066' && r <= '\u2069' // bidi embeddings and isolates
+}
+
+// Hangul syllables compose algorithmically rather than by table.
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// nfc brings runes to Normalization Form C: precomposed characters are
+// decomposed, combining marks are put in canonical order, and the result
+// is composed again. Runes composed into a starter take on the starter's
+// offset.
+func nfc(runes []sourceRune) []sourceRune {
+	decomposed := make([]sourceRune, 0, len(runes))
+	for _, sr := range runes {
+		decomposed = decompose(decomposed, sr)
+	}
+
+	// Canonical ordering: a stable sort of each run of combining marks by
+	// combining class.
+	for i := 0; i < len(decomposed); {
+		j := i
+		for j < len(decomposed) && combiningClasses[decomposed[j].r] != 0 {
+			j++
+		}
+		if j-i > 1 {
+			slices.SortStableFunc(decomposed[i:j], func(a, b sourceRune) int {
+				return int(combiningClasses[a.r]) - int(combiningClasses[b.r])
+			})
+		}
+		i = max(j, i+1)
+	}
+
+	// Canonical composition: each character joins the last starter unless
+	// a character in between blocks it.
+	out := decomposed[:0]
+	starter := -1
+	var lastClass uint8
+	for _, sr := range decomposed {
+		class := combiningClasses[sr.r]
+		if starter >= 0 && (lastClass < class || lastClass == 0 && len(out) == starter+1) {
+			if c, ok := compose(out[starter].r, sr.r); ok {
+				out[starter].r = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, sr)
+	}
+	return out
+}
+
+// decompose appends the full canonical decomposition of sr to dst.
+func decompose(dst []sourceRune, sr sourceRune) []sourceRune {
+	d, ok := nfcDecomposeOnly[sr.r]
+	if pair, found := nfcDecompositions()[sr.r]; found {
+		d, ok = pair[:], true
+	}
+	if !ok {
+		return append(dst, sr)
+	}
+	for _, r := range d {
+		dst = decompose(dst, sourceRune{r: r, at: sr.at})
+	}
+	return dst
+}
+
+// nfcDecompositions inverts nfcPairs.
+var nfcDecompositions = sync.OnceValue(func() map[rune][2]rune {
+	m := make(map[rune][2]rune, len(nfcPairs))
+	for pair, c := range nfcPairs {
+		m[c] = pair
+	}
+	return m
+})
+
+// compose returns the canonical composite of a and b, if there is one.
+func compose(a, b rune) (rune, bool) {
+	if a >= hangulLBase && a < hangulLBase+hangulLCount && b >= hangulVBase && b < hangulVBase+hangulVCount {
+		return hangulSBase + ((a-hangulLBase)*hangulVCount+(b-hangulVBase))*hangulTCount, true
+	}
+	if s := a - hangulSBase; s >= 0 && s < hangulSCount && s%hangulTCount == 0 && b > hangulTBase && b < hangulTBase+hangulTCount {
+		return a + (b - hangulTBase), true
+	}
+	c, ok := nfcPairs[[2]rune{a, b}]
+	return c, ok
+}
+
+// originalSpan returns the part of raw that became result[start:end],
+// given the offsets from normalize, or "" if it cannot be traced.
+func originalSpan(raw string, offsets map[int]int, start, end int) string {
+	from, ok1 := offsets[start]
+	to, ok2 := offsets[end]
+	if !ok1 || !ok2 || from > to || !utf8.ValidString(raw[from:to]) {
+		return ""
+	}
+	return raw[from:to]
+}
@@ -0,0 +1,111 @@
+package treeforge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		norm     Normalization
+		input    string
+		expected string
+	}{
+		{name: "nfd accents", norm: NormalizeNFC, input: "cafe\u0301.md", expected: "caf\u00e9.md"},
+		{name: "stacked marks", norm: NormalizeNFC, input: "e\u0323\u0302", expected: "\u1ec7"},
+		{name: "dakuten", norm: NormalizeNFC, input: "\u30ab\u3099\u30a4\u30c8\u3099.md", expected: "\u30ac\u30a4\u30c9.md"},
+		{name: "hangul jamo", norm: NormalizeNFC, input: "\u1112\u1161\u11ab", expected: "\ud55c"},
+		{name: "marks out of order", norm: NormalizeNFC, input: "e\u0302\u0323", expected: "\u1ec7"},
+		{name: "mark below a precomposed letter", norm: NormalizeNFC, input: "\u00ea\u0323", expected: "\u1ec7"},
+		{name: "reordered marks left over", norm: NormalizeNFC, input: "a\u0315\u0301\u0323", expected: "\u1ea1\u0301\u0315"},
+		{name: "blocked repeat mark", norm: NormalizeNFC, input: "a\u0308\u0308", expected: "\u00e4\u0308"},
+		{name: "two-part vowel", norm: NormalizeNFC, input: "\u0b47\u0b3e", expected: "\u0b4b"},
+		{name: "singletons", norm: NormalizeNFC, input: "\u212b\u2126\u037e", expected: "\u00c5\u03a9;"},
+		{name: "singleton then mark", norm: NormalizeNFC, input: "\u212b\u0301", expected: "\u01fa"},
+		{name: "composition exclusion", norm: NormalizeNFC, input: "\u0958", expected: "\u0915\u093c"},
+		{name: "non-starter decomposition", norm: NormalizeNFC, input: "a\u0344", expected: "\u00e4\u0301"},
+		{name: "no-break spaces", norm: NormalizeSpaces, input: "my\u00a0file\u3000x", expected: "my file x"},
+		{name: "zero-width", norm: NormalizeInvisible, input: "\ufeffma\u200bin\u200d.go\u00ad", expected: "main.go"},
+		{name: "full width", norm: NormalizeWidth, input: "\uff4d\uff41\uff49\uff4e\uff0e\uff47\uff4f", expected: "main.go"},
+		{name: "full-width slashes kept", norm: NormalizeWidth, input: "\uff41\uff0f\uff42\uff3c\uff43", expected: "a\uff0fb\uff3cc"},
+		{name: "smart quotes", norm: NormalizeQuotes, input: "\u201cit\u2019s\u201d", expected: `"it's"`},
+		{name: "disabled", norm: NormalizeNone, input: "cafe\u0301\u00a0", expected: "cafe\u0301\u00a0"},
+		{name: "only selected", norm: NormalizeSpaces, input: "\uff58\u00a0", expected: "\uff58 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := tt.norm.normalize(tt.input); got != tt.expected {
+				t.Errorf("normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseNormalization(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Normalization
+		hasError bool
+	}{
+		{input: "all", expected: NormalizeAll},
+		{input: "none", expected: NormalizeNone},
+		{input: "", expected: NormalizeNone},
+		{input: "nfc, spaces", expected: NormalizeNFC | NormalizeSpaces},
+		{input: "NFC,quotes", expected: NormalizeNFC | NormalizeQuotes},
+		{input: "nfkc", hasError: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseNormalization(tt.input)
+		if tt.hasError {
+			if err == nil {
+				t.Errorf("ParseNormalization(%q) expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("ParseNormalization(%q) = %v, %v; want %v", tt.input, got, err, tt.expected)
+		}
+		if back, _ := ParseNormalization(got.String()); back != got {
+			t.Errorf("ParseNormalization(%q.String()) = %v, want %v", got, back, got)
+		}
+	}
+}
+
+func TestParseNormalized(t *testing.T) {
+	lines := []string{
+		"\ufeffmy\u00a0app/",
+		"├─ src/",
+		"\u2502\u00a0 \u2514\u2500 cafe\u0301.go",
+		"\u2502  \u2514\u2500 ma\u200bin.go\u200b  # entry",
+		"└─ README.md",
+	}
+
+	tree, err := ParseLines(lines, Options{Normalize: NormalizeAll})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	if tree.Root != "my app" {
+		t.Errorf("ParseLines() Root = %q, want %q", tree.Root, "my app")
+	}
+	expected := []Entry{
+		{Path: "src", Kind: KindDir},
+		{Path: "src/caf\u00e9.go", Kind: KindFile, Original: "cafe\u0301.go"},
+		{Path: "src/main.go", Kind: KindFile, Original: "ma\u200bin.go\u200b"},
+		{Path: "README.md", Kind: KindFile},
+	}
+	if got := withoutLines(tree.Entries); !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseLines() mismatch:\n%s\ngot %q", cmpEntries(expected, got), got)
+	}
+
+	// Without normalization the no-break spaces defeat the indentation.
+	tree, err = ParseLines(lines, Options{})
+	if err != nil {
+		t.Fatalf("ParseLines() unexpected error: %v", err)
+	}
+	if got := tree.Entries[1].Path; got != "\u2514\u2500 cafe\u0301.go" {
+		t.Errorf("ParseLines() without normalization: Path = %q, want %q", got, "\u2514\u2500 cafe\u0301.go")
+	}
+}
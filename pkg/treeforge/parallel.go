@@ -0,0 +1,103 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+)
+
+// outcome is the result of one step run by a worker.
+type outcome struct {
+	index  int
+	result Result
+	err    error
+}
+
+// applyParallel runs plan.Steps on plan.Jobs workers. A step only starts
+// once the step creating its parent directory has succeeded; steps below
+// a directory that failed are not attempted. Results are reported in plan
+// order whatever order they finished in, and every failure is returned,
+// joined in plan order.
+func applyParallel(ctx context.Context, plan *Plan, fsys FS) (*Report, error) {
+	ready, children := stepDependencies(plan.Steps)
+
+	tasks := make(chan int)
+	done := make(chan outcome)
+	var wg sync.WaitGroup
+	for w := 0; w < plan.Jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range tasks {
+				res, err := createEntry(ctx, fsys, plan.Steps[i], plan)
+				done <- outcome{index: i, result: res, err: err}
+			}
+		}()
+	}
+
+	results := make([]*Result, len(plan.Steps))
+	errs := make([]error, len(plan.Steps))
+	queue, inFlight := ready, 0
+	for inFlight > 0 || (len(queue) > 0 && ctx.Err() == nil) {
+		// A nil channel never receives, so nothing is handed out once the
+		// queue is empty or the context is done.
+		var send chan int
+		next := -1
+		if len(queue) > 0 && ctx.Err() == nil {
+			send, next = tasks, queue[0]
+		}
+
+		select {
+		case send <- next:
+			queue = queue[1:]
+			inFlight++
+		case o := <-done:
+			inFlight--
+			if o.err != nil {
+				errs[o.index] = o.err
+				continue
+			}
+			results[o.index] = &o.result
+			if plan.OnResult != nil {
+				plan.OnResult(*results[o.index])
+			}
+			queue = append(queue, children[o.index]...)
+		}
+	}
+	close(tasks)
+	wg.Wait()
+
+	report := &Report{}
+	for _, res := range results {
+		if res != nil {
+			report.Results = append(report.Results, *res)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	return report, errors.Join(errs...)
+}
+
+// stepDependencies finds, for each step, the directory step that must
+// finish first. It returns the steps that can start straight away and,
+// for each directory step, the steps waiting on it, both in plan order.
+func stepDependencies(steps []Step) (ready []int, children [][]int) {
+	dirs := map[string]int{}
+	for i, step := range steps {
+		if _, seen := dirs[step.Target]; !seen && step.Entry.Kind == KindDir {
+			dirs[step.Target] = i
+		}
+	}
+
+	children = make([][]int, len(steps))
+	for i, step := range steps {
+		if parent, ok := dirs[filepath.Dir(step.Target)]; ok {
+			children[parent] = append(children[parent], i)
+		} else {
+			ready = append(ready, i)
+		}
+	}
+	return ready, children
+}
@@ -0,0 +1,151 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// orderFS wraps an FS, recording the order in which paths are first
+// touched and failing writes to the paths in fail.
+type orderFS struct {
+	FS
+	fail map[string]bool
+
+	mu    sync.Mutex
+	order map[string]int
+}
+
+func newOrderFS(fail ...string) *orderFS {
+	o := &orderFS{FS: NewMemFS(), fail: map[string]bool{}, order: map[string]int{}}
+	for _, p := range fail {
+		o.fail[p] = true
+	}
+	return o
+}
+
+func (o *orderFS) touch(path string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.order[path]; !ok {
+		o.order[path] = len(o.order)
+	}
+	if o.fail[path] {
+		return &fs.PathError{Op: "write", Path: path, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+func (o *orderFS) MkdirAll(path string, perm fs.FileMode) error {
+	if err := o.touch(path); err != nil {
+		return err
+	}
+	return o.FS.MkdirAll(path, perm)
+}
+
+func (o *orderFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
+	if err := o.touch(path); err != nil {
+		return err
+	}
+	return o.FS.WriteFile(path, data, perm)
+}
+
+func wideTree() *Tree {
+	tree := &Tree{Root: "app"}
+	for i := 0; i < 20; i++ {
+		dir := fmt.Sprintf("pkg%02d", i)
+		tree.Entries = append(tree.Entries,
+			Entry{Path: dir, Kind: KindDir},
+			Entry{Path: dir + "/sub", Kind: KindDir},
+			Entry{Path: dir + "/sub/a.go", Kind: KindFile},
+			Entry{Path: dir + "/b.go", Kind: KindFile},
+		)
+	}
+	return tree
+}
+
+func TestApplyParallelMatchesSequential(t *testing.T) {
+	seqFS := NewMemFS()
+	seq, err := Apply(context.Background(), NewPlan(wideTree(), "out"), seqFS)
+	if err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	plan := NewPlan(wideTree(), "out")
+	plan.Jobs = 8
+	seen := 0
+	plan.OnResult = func(Result) { seen++ } // unsynchronized: calls must not overlap
+	ofs := newOrderFS()
+	par, err := Apply(context.Background(), plan, ofs)
+	if err != nil {
+		t.Fatalf("Apply() with Jobs unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(par.Results, seq.Results) {
+		t.Errorf("parallel results differ from sequential ones")
+	}
+	if seen != len(plan.Steps) {
+		t.Errorf("OnResult called %d times, want %d", seen, len(plan.Steps))
+	}
+	if got, want := ofs.FS.(*MemFS).Paths(), seqFS.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("parallel Apply created %v, want %v", got, want)
+	}
+
+	// Every directory step is touched before anything inside it.
+	for _, step := range plan.Steps {
+		parent := filepath.Dir(step.Target)
+		if ofs.order[step.Target] < ofs.order[parent] {
+			t.Errorf("%s was created before its parent %s", step.Target, parent)
+		}
+	}
+}
+
+func TestApplyParallelErrors(t *testing.T) {
+	plan := NewPlan(wideTree(), "out")
+	plan.Jobs = 4
+	ofs := newOrderFS("out/app/pkg03", "out/app/pkg07/b.go")
+
+	report, err := Apply(context.Background(), plan, ofs)
+	if err == nil {
+		t.Fatal("Apply() expected an error")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "pkg03") || !strings.Contains(msg, "pkg07/b.go") {
+		t.Errorf("Apply() error = %q, want both failures", msg)
+	}
+	if strings.Index(msg, "pkg03") > strings.Index(msg, "pkg07") {
+		t.Errorf("Apply() error lists failures out of plan order: %q", msg)
+	}
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Apply() error does not wrap fs.ErrPermission")
+	}
+
+	// Nothing below the failed directory is attempted; everything else is.
+	if _, ok := ofs.order["out/app/pkg03/sub"]; ok {
+		t.Errorf("Apply() created a child of a failed directory")
+	}
+	if got, want := len(report.Results), len(plan.Steps)-5; got != want {
+		t.Errorf("Apply() reported %d results, want %d", got, want)
+	}
+}
+
+func TestApplyParallelCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	plan := NewPlan(wideTree(), "out")
+	plan.Jobs = 4
+	report, err := Apply(ctx, plan, NewMemFS())
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Apply() error = %v, want context.Canceled", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("Apply() reported %d results after cancel, want 0", len(report.Results))
+	}
+}
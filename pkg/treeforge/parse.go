@@ -0,0 +1,519 @@
+package treeforge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Options controls how a tree diagram is parsed.
+type Options struct {
+	// RootName overrides the root directory name taken from the first line.
+	RootName string
+
+	// Normalize selects the Unicode clean-ups applied to names. The zero
+	// value leaves input untouched.
+	Normalize Normalization
+
+	// Strict rejects diagrams the forgiving parser would have to guess
+	// at, reporting every problem in a *ParseError.
+	Strict bool
+
+	// Include returns the lines of the fragment named by an
+	// "@include path" line. Paths in a fragment are joined to the
+	// fragment's directory first; paths in the main input are passed as
+	// written. If Include is nil, @include lines are reported as problems.
+	Include func(path string) ([]string, error)
+
+	// Vars are the variables "# if: expr" conditions are evaluated
+	// against, as text/template data; unset variables are false.
+	Vars map[string]any
+}
+
+// maxLineLength lifts bufio.Scanner's 64KB default so that generated
+// trees with very long names still parse.
+const maxLineLength = 1<<31 - 1
+
+// Parse reads a tree diagram from r. Use a Parser to handle entries as
+// they are read instead.
+func Parse(r io.Reader, opts Options) (*Tree, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineLength)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ParseLines(lines, opts)
+}
+
+// ParseLines parses a tree diagram that has already been split into lines.
+func ParseLines(lines []string, opts Options) (*Tree, error) {
+	p := newParser(opts)
+	entries, roots, err := p.parseLines(lines)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{
+		Root:     determineRootName(opts.RootName, roots),
+		Entries:  entries,
+		Excluded: p.excluded,
+	}, nil
+}
+
+// ParseTree parses lines into entries relative to the root on lines[0].
+// When the diagram has several top-level roots, or none, entries are
+// relative to the directory the roots would be created in.
+func ParseTree(lines []string) ([]Entry, error) {
+	entries, _, err := newParser(Options{}).parseLines(lines)
+	return entries, err
+}
+
+// parseLines parses a whole diagram with p.
+func (p *parser) parseLines(lines []string) ([]Entry, []string, error) {
+	if len(lines) == 0 {
+		return nil, nil, errors.New("empty tree")
+	}
+
+	first, err := p.begin(lines[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if first {
+		p.add(1, lines[0])
+	}
+	for i := 1; i < len(lines); i++ {
+		p.add(i+1, lines[i])
+	}
+	p.checkBlocks()
+	if len(p.issues) > 0 {
+		return nil, nil, &ParseError{Issues: p.issues}
+	}
+	return p.finish()
+}
+
+// parser turns entry lines into entries, tracking the directory open at
+// each indentation level.
+type parser struct {
+	opts        Options
+	entries     []Entry
+	levelParent map[int]string
+
+	// roots lists the top-level roots seen so far. glyphs records whether
+	// the root's children are drawn with branches, which is what lets a
+	// bare "name/" line start another root.
+	roots  []root
+	glyphs bool
+
+	// file is the fragment being parsed, as passed to Options.Include, or
+	// empty for the main input. chain lists it and the fragments that
+	// included it, to catch include cycles.
+	file  string
+	chain []string
+
+	// Entries left out by "# if:" conditions, with the open condition
+	// blocks and the directory whose children are being left out.
+	excluded []Exclusion
+	blocks   []block
+	skip     *skipped
+
+	// singleRoot is set when entries are handed out as they are parsed
+	// and so cannot be moved under a root that only turns up later; a
+	// second root is then reported in err.
+	singleRoot bool
+	err        error
+
+	// Strict mode state: the deepest level the next line may use, the
+	// previous entry if it was a file, the name column first seen at each
+	// level, and the problems found.
+	maxLevel int
+	lastFile string
+	columns  map[int]int
+	issues   []Issue
+}
+
+// root is a top-level directory line and the index of its first entry
+// and first excluded entry.
+type root struct {
+	name     string
+	line     int
+	first    int
+	excluded int
+}
+
+func newParser(opts Options) *parser {
+	return &parser{
+		opts:        opts,
+		entries:     make([]Entry, 0), // Initialize as empty slice, not nil
+		levelParent: map[int]string{0: ""},
+		columns:     map[int]int{},
+	}
+}
+
+// begin looks at the first line: a bare or merely indented name starts
+// the first root, while a branch means the tree has no root line and the
+// line is itself an entry, reported by isEntry.
+func (p *parser) begin(line string) (isEntry bool, err error) {
+	// Check root line validity
+	if strings.TrimSpace(line) == "" {
+		return false, errors.New("invalid root line")
+	}
+	l, ok := splitLine(line, p.opts.Normalize)
+	if _, include := includePath(l); !ok || l.branched() || include {
+		return true, nil
+	}
+	if l.name == "" {
+		return false, errors.New("invalid root line")
+	}
+	p.startRoot(1, l.name)
+	return false, nil
+}
+
+// startRoot begins a new top-level root named name on line num.
+func (p *parser) startRoot(num int, name string) {
+	p.roots = append(p.roots, root{name: name, line: num, first: len(p.entries), excluded: len(p.excluded)})
+	p.levelParent = map[int]string{0: ""}
+	p.maxLevel, p.lastFile = 0, ""
+}
+
+// finish returns the entries and root names. Entries under several roots
+// are moved below a directory entry for their root.
+func (p *parser) finish() ([]Entry, []string, error) {
+	names := make([]string, len(p.roots))
+	for i, r := range p.roots {
+		names[i] = r.name
+	}
+	if len(p.roots) < 2 {
+		return p.entries, names, nil
+	}
+
+	entries := make([]Entry, 0, len(p.entries)+len(p.roots))
+	for i, r := range p.roots {
+		end, excludedEnd := len(p.entries), len(p.excluded)
+		if i+1 < len(p.roots) {
+			end, excludedEnd = p.roots[i+1].first, p.roots[i+1].excluded
+		}
+		entries = append(entries, Entry{Path: r.name, Kind: KindDir, Line: r.line, File: p.file})
+		for _, e := range p.entries[r.first:end] {
+			e.Path = filepath.Join(r.name, e.Path)
+			entries = append(entries, e)
+		}
+		for j := r.excluded; j < excludedEnd; j++ {
+			p.excluded[j].Entry.Path = filepath.Join(r.name, p.excluded[j].Entry.Path)
+		}
+	}
+	return entries, names, nil
+}
+
+// parsedLine is an entry line taken apart.
+type parsedLine struct {
+	level    int
+	prefix   string // indentation and branch glyphs before the name
+	name     string // without the trailing slash
+	isDir    bool
+	original string // name as written, if normalization changed it
+	comment  string // text of a trailing comment, without the '#'
+}
+
+// branched reports whether the line has branch glyphs before its name,
+// rather than plain indentation.
+func (l parsedLine) branched() bool {
+	return strings.TrimSpace(l.prefix) != ""
+}
+
+// splitLine extracts the entry on a line; ok is false for lines with no
+// entry, such as blank lines and comments.
+func splitLine(raw string, norm Normalization) (l parsedLine, ok bool) {
+	line, offsets := norm.normalize(raw)
+	if strings.TrimSpace(line) == "" {
+		return l, false
+	}
+
+	// Remove comment, keeping its text for conditions
+	cut := cutComment(line)
+	l.comment = comment(line, cut)
+	line = cut
+
+	// Get indentation level
+	level, rest := consumeIndent(line)
+
+	// Remove branch decorations
+	rest = trimBranch(rest)
+
+	// Extract name
+	name := strings.TrimSpace(rest)
+	if name == "" {
+		return l, false
+	}
+	start := len(line) - len(strings.TrimLeftFunc(rest, unicode.IsSpace))
+
+	// Check if directory
+	l = parsedLine{level: level, prefix: line[:start], isDir: strings.HasSuffix(name, "/"), comment: l.comment}
+	l.name = strings.TrimSuffix(name, "/")
+
+	// Remember how the name was written if normalization changed it
+	if norm != NormalizeNone {
+		if o := originalSpan(raw, offsets, start, start+len(l.name)); o != l.name {
+			l.original = o
+		}
+	}
+	return l, true
+}
+
+// add parses the line numbered num.
+func (p *parser) add(num int, raw string) {
+	l, ok := splitLine(raw, p.opts.Normalize)
+	if !ok {
+		p.blockComment(num, l.comment)
+		return
+	}
+	if path, ok := includePath(l); ok {
+		p.include(num, l.level, path, p.exclusion(num, path, l))
+		return
+	}
+	if l.level == 0 && l.branched() {
+		p.glyphs = true
+	} else if !l.branched() && p.glyphs && l.isDir && len(p.roots) > 0 {
+		// A bare directory after indented entries is another root.
+		if p.singleRoot {
+			p.err = fmt.Errorf("line %d: %s/ starts a second top-level root, which cannot be streamed", num, l.name)
+			return
+		}
+		p.startRoot(num, l.name)
+		return
+	}
+
+	// Build relative path
+	parent := p.levelParent[l.level]
+	var relPath string
+	if parent == "" {
+		relPath = l.name
+	} else {
+		relPath = filepath.Join(parent, l.name)
+	}
+
+	reason := p.exclusion(num, relPath, l)
+	if p.opts.Strict {
+		p.checkStrict(num, relPath, l)
+	}
+
+	if l.isDir {
+		p.keep(Entry{Path: relPath, Kind: KindDir, Line: num, File: p.file, Original: l.original}, reason)
+		p.levelParent[l.level+1] = relPath
+		// Clear deeper levels (sibling branches)
+		for k := range p.levelParent {
+			if k > l.level+1 {
+				delete(p.levelParent, k)
+			}
+		}
+	} else {
+		p.keep(Entry{Path: relPath, Kind: KindFile, Line: num, File: p.file, Original: l.original}, reason)
+	}
+}
+
+// determineRootName picks the directory, relative to the parent, that
+// entries are created in: the override if given, the single root, or the
+// parent itself for "." roots, root-less trees and trees with several roots.
+func determineRootName(rootName string, roots []string) string {
+	if rootName != "" {
+		return rootName
+	}
+	if len(roots) != 1 {
+		return "."
+	}
+
+	root := strings.TrimSpace(roots[0])
+	root = strings.TrimSuffix(root, "/")
+	if root == "" {
+		return "output"
+	}
+	return filepath.Clean(root)
+}
+
+func cutComment(s string) string {
+	// Priority: " #" (space + hash)
+	if idx := strings.Index(s, " #"); idx >= 0 {
+		return s[:idx]
+	}
+
+	// Otherwise, check if # is preceded by space, tab, │, or |
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '#' {
+			if i == 0 {
+				return ""
+			}
+			if i > 0 {
+				prev := runes[i-1]
+				if prev == ' ' || prev == '\t' || prev == '│' || prev == '|' {
+					return string(runes[:i])
+				}
+			}
+		}
+	}
+
+	return s
+}
+
+// Helper functions for consumeIndent
+func matchUnicodeBoxPattern(runes []rune, pos int) bool {
+	return pos+2 < len(runes) && runes[pos] == '│' && runes[pos+1] == ' ' && runes[pos+2] == ' '
+}
+
+func matchPipePattern(runes []rune, pos int) bool {
+	return pos+2 < len(runes) && runes[pos] == '|' && runes[pos+1] == ' ' && runes[pos+2] == ' '
+}
+
+func matchThreeSpacePattern(runes []rune, pos int) bool {
+	return pos+2 < len(runes) && runes[pos] == ' ' && runes[pos+1] == ' ' && runes[pos+2] == ' '
+}
+
+func consumeSinglePipeWithSpaces(runes []rune, pos int) (int, bool) {
+	if pos >= len(runes) {
+		return pos, false
+	}
+
+	if runes[pos] == '│' || runes[pos] == '|' {
+		start := pos
+		pos++
+		// Consume trailing spaces
+		for pos < len(runes) && runes[pos] == ' ' {
+			pos++
+		}
+		if pos > start+1 {
+			// Had spaces after │ or |, count as one level
+			return pos, true
+		}
+		// No spaces, revert
+		return start, false
+	}
+
+	return pos, false
+}
+
+func consumeIndent(s string) (level int, rest string) {
+	runes := []rune(s)
+	pos := 0
+
+	for pos < len(runes) {
+		// Try '│  ' (U+2502 + 2 spaces)
+		if matchUnicodeBoxPattern(runes, pos) {
+			level++
+			pos += 3
+			continue
+		}
+
+		// Try '|  ' (pipe + 2 spaces)
+		if matchPipePattern(runes, pos) {
+			level++
+			pos += 3
+			continue
+		}
+
+		// Try '   ' (3 spaces)
+		if matchThreeSpacePattern(runes, pos) {
+			level++
+			pos += 3
+			continue
+		}
+
+		// Try '\t' (single tab)
+		if runes[pos] == '\t' {
+			level++
+			pos++
+			continue
+		}
+
+		// Try single '│' or '|' followed by spaces
+		if newPos, found := consumeSinglePipeWithSpaces(runes, pos); found {
+			level++
+			pos = newPos
+			continue
+		}
+
+		// Check if it's a leading space that doesn't form a pattern
+		if runes[pos] == ' ' {
+			// Skip single spaces that don't form part of an indent token
+			pos++
+			continue
+		}
+
+		// No more indent tokens
+		break
+	}
+
+	return level, string(runes[pos:])
+}
+
+// Helper functions for trimBranch
+func trimLeadingWhitespace(runes []rune) []rune {
+	for len(runes) > 0 && (runes[0] == ' ' || runes[0] == '\t') {
+		runes = runes[1:]
+	}
+	return runes
+}
+
+func removeBranchPrefixes(runes []rune) []rune {
+	branches := []string{"├─", "└─", "|--", "`--", "+--"}
+	for {
+		trimmed := false
+		for _, branch := range branches {
+			branchRunes := []rune(branch)
+			if len(runes) >= len(branchRunes) {
+				match := true
+				for i, br := range branchRunes {
+					if runes[i] != br {
+						match = false
+						break
+					}
+				}
+				if match {
+					runes = runes[len(branchRunes):]
+					trimmed = true
+					// After removing a branch token, trim spaces/tabs again
+					runes = trimLeadingWhitespace(runes)
+					break
+				}
+			}
+		}
+		if !trimmed {
+			break
+		}
+	}
+	return runes
+}
+
+func removeResidualBoxDrawing(runes []rune) []rune {
+	for len(runes) > 0 {
+		r := runes[0]
+		if r == '─' || r == '—' || r == '│' || r == '|' || r == '-' || r == ' ' || r == '\t' {
+			runes = runes[1:]
+		} else {
+			break
+		}
+	}
+	return runes
+}
+
+func trimBranch(s string) string {
+	runes := []rune(s)
+
+	// First, trim any leading spaces or tabs
+	runes = trimLeadingWhitespace(runes)
+
+	// Remove branch prefixes repeatedly
+	runes = removeBranchPrefixes(runes)
+
+	// Remove residual box drawing characters, dashes, pipes, and spaces between them
+	runes = removeResidualBoxDrawing(runes)
+
+	// Final trim of any remaining leading spaces
+	result := string(runes)
+	return strings.TrimLeft(result, " \t")
+}
@@ -1,7 +1,8 @@
-package main
+package treeforge
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -318,6 +319,7 @@ func TestParseTree(t *testing.T) {
 				return
 			}
 
+			result = withoutLines(result)
 			if !reflect.DeepEqual(result, tt.expected) {
 				t.Errorf("ParseTree() mismatch:\n%s", cmpEntries(tt.expected, result))
 			}
@@ -325,6 +327,63 @@ func TestParseTree(t *testing.T) {
 	}
 }
 
+func TestParse(t *testing.T) {
+	input := "myapp/\n├─ src/\n│  └─ main.go\n└─ README.md\n"
+
+	tree, err := Parse(strings.NewReader(input), Options{})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if tree.Root != "myapp" {
+		t.Errorf("Parse() Root = %q, want %q", tree.Root, "myapp")
+	}
+	expected := []Entry{
+		{Path: "src", Kind: KindDir},
+		{Path: "src/main.go", Kind: KindFile},
+		{Path: "README.md", Kind: KindFile},
+	}
+	if got := withoutLines(tree.Entries); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Parse() mismatch:\n%s", cmpEntries(expected, got))
+	}
+
+	tree, err = Parse(strings.NewReader(input), Options{RootName: "custom"})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if tree.Root != "custom" {
+		t.Errorf("Parse() with RootName: Root = %q, want %q", tree.Root, "custom")
+	}
+
+	if _, err := Parse(strings.NewReader(""), Options{}); err == nil {
+		t.Errorf("Parse() expected error for empty input")
+	}
+}
+
+func TestParseTreeLines(t *testing.T) {
+	lines := []string{
+		"myapp/",
+		"├─ src/",
+		"",
+		"│  └─ main.go # entry point",
+		"└─ README.md",
+	}
+
+	entries, err := ParseTree(lines)
+	if err != nil {
+		t.Fatalf("ParseTree() unexpected error: %v", err)
+	}
+
+	want := []int{2, 4, 5}
+	if len(entries) != len(want) {
+		t.Fatalf("ParseTree() got %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if e.Line != want[i] {
+			t.Errorf("%s: Line = %d, want %d", e.Path, e.Line, want[i])
+		}
+	}
+}
+
 func TestCutComment(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -820,6 +879,225 @@ func TestRemoveResidualBoxDrawing(t *testing.T) {
 	}
 }
 
+func TestParseRoots(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		opts     Options
+		root     string
+		expected []Entry
+	}{
+		{
+			name: "several roots",
+			lines: []string{
+				"frontend/",
+				"├─ src/",
+				"│  └─ app.ts",
+				"└─ package.json",
+				"backend/",
+				"└─ main.go",
+			},
+			root: ".",
+			expected: []Entry{
+				{Path: "frontend", Kind: KindDir},
+				{Path: "frontend/src", Kind: KindDir},
+				{Path: "frontend/src/app.ts", Kind: KindFile},
+				{Path: "frontend/package.json", Kind: KindFile},
+				{Path: "backend", Kind: KindDir},
+				{Path: "backend/main.go", Kind: KindFile},
+			},
+		},
+		{
+			name: "several roots under an explicit name",
+			lines: []string{
+				"frontend/",
+				"└─ index.html",
+				"backend/",
+				"└─ main.go",
+			},
+			opts: Options{RootName: "project"},
+			root: "project",
+			expected: []Entry{
+				{Path: "frontend", Kind: KindDir},
+				{Path: "frontend/index.html", Kind: KindFile},
+				{Path: "backend", Kind: KindDir},
+				{Path: "backend/main.go", Kind: KindFile},
+			},
+		},
+		{
+			name: "current directory",
+			lines: []string{
+				".",
+				"├── cmd/",
+				"│   └── main.go",
+				"└── go.mod",
+			},
+			root: ".",
+			expected: []Entry{
+				{Path: "cmd", Kind: KindDir},
+				{Path: "cmd/main.go", Kind: KindFile},
+				{Path: "go.mod", Kind: KindFile},
+			},
+		},
+		{
+			name: "no root line",
+			lines: []string{
+				"├─ src/",
+				"│  └─ main.go",
+				"└─ README.md",
+			},
+			root: ".",
+			expected: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/main.go", Kind: KindFile},
+				{Path: "README.md", Kind: KindFile},
+			},
+		},
+		{
+			name: "indented diagram keeps its root",
+			lines: []string{
+				"  myapp/",
+				"  ├── a.txt",
+				"  └── b/",
+			},
+			root: "myapp",
+			expected: []Entry{
+				{Path: "a.txt", Kind: KindFile},
+				{Path: "b", Kind: KindDir},
+			},
+		},
+		{
+			name: "bare file stays under the root",
+			lines: []string{
+				"myapp/",
+				"├─ src/",
+				"README.md",
+			},
+			root: "myapp",
+			expected: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "README.md", Kind: KindFile},
+			},
+		},
+		{
+			name: "plain indentation has a single root",
+			lines: []string{
+				"myapp/",
+				"src/",
+				"\tmain.go",
+				"docs/",
+			},
+			root: "myapp",
+			expected: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/main.go", Kind: KindFile},
+				{Path: "docs", Kind: KindDir},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := ParseLines(tt.lines, tt.opts)
+			if err != nil {
+				t.Fatalf("ParseLines() unexpected error: %v", err)
+			}
+			if tree.Root != tt.root {
+				t.Errorf("ParseLines() Root = %q, want %q", tree.Root, tt.root)
+			}
+			if got := withoutLines(tree.Entries); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseLines() mismatch:\n%s", cmpEntries(tt.expected, got))
+			}
+		})
+	}
+}
+
+func TestDetermineRootName(t *testing.T) {
+	tests := []struct {
+		name     string
+		rootName string
+		roots    []string
+		expected string
+	}{
+		{
+			name:     "explicit root name",
+			rootName: "custom-root",
+			roots:    []string{"myapp/"},
+			expected: "custom-root",
+		},
+		{
+			name:     "extract from first line",
+			rootName: "",
+			roots:    []string{"myapp/"},
+			expected: "myapp",
+		},
+		{
+			name:     "extract from first line without slash",
+			rootName: "",
+			roots:    []string{"myapp"},
+			expected: "myapp",
+		},
+		{
+			name:     "empty first line falls back to output",
+			rootName: "",
+			roots:    []string{""},
+			expected: "output",
+		},
+		{
+			name:     "whitespace only first line falls back to output",
+			rootName: "",
+			roots:    []string{"   "},
+			expected: "output",
+		},
+		{
+			name:     "current directory",
+			rootName: "",
+			roots:    []string{"./"},
+			expected: ".",
+		},
+		{
+			name:     "several roots",
+			rootName: "",
+			roots:    []string{"frontend", "backend"},
+			expected: ".",
+		},
+		{
+			name:     "several roots under an explicit name",
+			rootName: "project",
+			roots:    []string{"frontend", "backend"},
+			expected: "project",
+		},
+		{
+			name:     "no root line",
+			rootName: "",
+			roots:    nil,
+			expected: ".",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := determineRootName(tt.rootName, tt.roots)
+			if result != tt.expected {
+				t.Errorf("determineRootName() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+// withoutLines clears Line so entries can be compared by path and kind alone
+func withoutLines(entries []Entry) []Entry {
+	if entries == nil {
+		return nil
+	}
+	out := make([]Entry, len(entries))
+	for i, e := range entries {
+		e.Line = 0
+		out[i] = e
+	}
+	return out
+}
+
 // cmpEntries provides a detailed comparison between expected and actual entries for debugging
 func cmpEntries(want, got []Entry) string {
 	result := ""
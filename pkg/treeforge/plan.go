@@ -0,0 +1,94 @@
+package treeforge
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// Plan is the set of filesystem changes needed to materialize a Tree.
+type Plan struct {
+	// Base is the directory the tree root maps to.
+	Base  string
+	Steps []Step
+
+	// Excluded lists the steps left out because the tree excluded them.
+	Excluded []ExcludedStep
+
+	// Force overwrites files that already exist instead of skipping them.
+	// It is shorthand for OnConflict: ConflictOverwrite.
+	Force bool
+
+	// OnConflict decides what happens to file entries whose target
+	// already exists. The zero value skips them.
+	OnConflict Conflict
+
+	// Ask is called under ConflictAsk with each existing file and its
+	// current contents, and returns what to do with it. Calls never
+	// overlap, even with Jobs. If Ask is nil, existing files are skipped.
+	Ask   func(step Step, existing []byte) (Conflict, error)
+	askMu sync.Mutex
+
+	// Jobs is the number of steps Apply may run at once. Values below 2
+	// apply the steps one by one, in order.
+	Jobs int
+
+	// OnResult, if set, is called as each step completes, for progress
+	// reporting or checkpointing. Calls never overlap, even with Jobs.
+	OnResult func(Result)
+}
+
+// Step is one entry of a Plan together with its target path.
+type Step struct {
+	Entry  Entry
+	Target string
+}
+
+// ExcludedStep is a step that will not be applied, and why.
+type ExcludedStep struct {
+	Step   Step
+	Reason string
+}
+
+// NewPlan maps tree onto parent, placing its root directory inside it.
+func NewPlan(tree *Tree, parent string) *Plan {
+	base := filepath.Join(parent, tree.Root)
+	plan := &Plan{Base: base, Steps: make([]Step, 0, len(tree.Entries))}
+	for _, entry := range tree.Entries {
+		plan.Steps = append(plan.Steps, Step{
+			Entry:  entry,
+			Target: filepath.Join(base, entry.Path),
+		})
+	}
+	for _, ex := range tree.Excluded {
+		plan.Excluded = append(plan.Excluded, ExcludedStep{
+			Step:   Step{Entry: ex.Entry, Target: filepath.Join(base, ex.Entry.Path)},
+			Reason: ex.Reason,
+		})
+	}
+	return plan
+}
+
+// Count returns the number of steps whose entry is of the given kind.
+func (p *Plan) Count(kind Kind) int {
+	count := 0
+	for _, step := range p.Steps {
+		if step.Entry.Kind == kind {
+			count++
+		}
+	}
+	return count
+}
+
+// Resume drops the steps whose targets are in done, as recorded by an
+// earlier run that did not finish, and returns how many were dropped.
+func (p *Plan) Resume(done map[string]bool) int {
+	kept := p.Steps[:0]
+	for _, step := range p.Steps {
+		if !done[step.Target] {
+			kept = append(kept, step)
+		}
+	}
+	dropped := len(p.Steps) - len(kept)
+	p.Steps = kept
+	return dropped
+}
@@ -0,0 +1,63 @@
+package treeforge
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewPlan(t *testing.T) {
+	tree := &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile},
+			{Path: "README.md", Kind: KindFile},
+		},
+	}
+
+	plan := NewPlan(tree, "/parent")
+
+	if want := filepath.Join("/parent", "myapp"); plan.Base != want {
+		t.Errorf("NewPlan() Base = %q, want %q", plan.Base, want)
+	}
+	if len(plan.Steps) != len(tree.Entries) {
+		t.Fatalf("NewPlan() got %d steps, want %d", len(plan.Steps), len(tree.Entries))
+	}
+	if want := filepath.Join("/parent", "myapp", "src", "main.go"); plan.Steps[1].Target != want {
+		t.Errorf("NewPlan() Target = %q, want %q", plan.Steps[1].Target, want)
+	}
+	if got := plan.Count(KindDir); got != 1 {
+		t.Errorf("Count(KindDir) = %d, want 1", got)
+	}
+	if got := plan.Count(KindFile); got != 2 {
+		t.Errorf("Count(KindFile) = %d, want 2", got)
+	}
+}
+
+func TestPlanResume(t *testing.T) {
+	tree := &Tree{
+		Root: "myapp",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile},
+			{Path: "README.md", Kind: KindFile},
+		},
+	}
+
+	// A first run gets through the first two entries.
+	first := NewPlan(&Tree{Root: tree.Root, Entries: tree.Entries[:2]}, "/parent")
+	done := map[string]bool{}
+	first.OnResult = func(res Result) { done[res.Step.Target] = true }
+	if _, err := Apply(context.Background(), first, NewMemFS()); err != nil {
+		t.Fatalf("Apply() unexpected error: %v", err)
+	}
+
+	plan := NewPlan(tree, "/parent")
+	if got := plan.Resume(done); got != 2 {
+		t.Errorf("Resume() dropped %d steps, want 2", got)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].Entry.Path != "README.md" {
+		t.Errorf("Resume() left steps %v, want only README.md", plan.Steps)
+	}
+}
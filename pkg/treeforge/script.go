@@ -0,0 +1,205 @@
+package treeforge
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ScriptFormat selects the language WriteScript emits.
+type ScriptFormat int
+
+const (
+	ScriptShell ScriptFormat = iota
+	ScriptPowerShell
+	ScriptMakefile
+)
+
+// ParseScriptFormat accepts "sh", "powershell" or "makefile".
+func ParseScriptFormat(name string) (ScriptFormat, error) {
+	switch strings.ToLower(name) {
+	case "sh", "shell":
+		return ScriptShell, nil
+	case "powershell", "ps1", "pwsh":
+		return ScriptPowerShell, nil
+	case "makefile", "make":
+		return ScriptMakefile, nil
+	}
+	return 0, fmt.Errorf("unknown script format %q (want sh, powershell or makefile)", name)
+}
+
+// scriptDialect renders individual operations in one script language.
+type scriptDialect interface {
+	header(w io.Writer, plan *Plan)
+	mkdir(w io.Writer, path string)
+	file(w io.Writer, path string, content []byte, force bool)
+}
+
+// WriteScript writes a script that reproduces plan when run from the
+// directory the plan's paths are relative to. Existing files are left
+// alone unless plan.Force is set or plan.OnConflict is ConflictOverwrite,
+// matching Apply; scripts cannot back up, rename or ask.
+func WriteScript(w io.Writer, plan *Plan, format ScriptFormat) error {
+	var d scriptDialect
+	switch format {
+	case ScriptPowerShell:
+		d = powerShellDialect{}
+	case ScriptMakefile:
+		d = makefileDialect{}
+	default:
+		d = shellDialect{}
+	}
+
+	bw := bufio.NewWriter(w)
+	d.header(bw, plan)
+	d.mkdir(bw, scriptPath(plan.Base))
+
+	made := map[string]bool{filepath.Clean(plan.Base): true}
+	for _, step := range plan.Steps {
+		target := filepath.Clean(step.Target)
+		if step.Entry.Kind == KindDir {
+			d.mkdir(bw, scriptPath(target))
+			made[target] = true
+			continue
+		}
+		if dir := filepath.Dir(target); !made[dir] {
+			d.mkdir(bw, scriptPath(dir))
+			made[dir] = true
+		}
+		d.file(bw, scriptPath(target), step.Entry.Content, plan.Force || plan.OnConflict == ConflictOverwrite)
+	}
+	return bw.Flush()
+}
+
+func scriptPath(path string) string {
+	return filepath.ToSlash(path)
+}
+
+// commentSafe keeps a path from breaking out of a single-line comment.
+func commentSafe(s string) string {
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s)
+}
+
+// shellQuote quotes s for POSIX sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// heredocDelimiter returns a delimiter that does not occur as a line of content.
+func heredocDelimiter(content string) string {
+	delim := "TREEFORGE_EOF"
+	for i := 1; containsLine(content, delim); i++ {
+		delim = fmt.Sprintf("TREEFORGE_EOF_%d", i)
+	}
+	return delim
+}
+
+func containsLine(content, line string) bool {
+	for _, l := range strings.Split(content, "\n") {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
+
+// printfEscape makes content safe as a printf format with no arguments,
+// keeping it on a single line.
+func printfEscape(content string) string {
+	r := strings.NewReplacer(`\`, `\\`, "%", "%%", "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return r.Replace(content)
+}
+
+type shellDialect struct{}
+
+func (shellDialect) header(w io.Writer, plan *Plan) {
+	fmt.Fprintf(w, "#!/bin/sh\n# Generated by treeforge: creates %s\nset -e\n\n", commentSafe(scriptPath(plan.Base)))
+}
+
+func (shellDialect) mkdir(w io.Writer, path string) {
+	fmt.Fprintf(w, "mkdir -p %s\n", shellQuote(path))
+}
+
+func (shellDialect) file(w io.Writer, path string, content []byte, force bool) {
+	q := shellQuote(path)
+	write := ": > " + q
+	if len(content) > 0 {
+		write = shellWrite(q, string(content))
+	}
+	if force {
+		fmt.Fprintf(w, "%s\n", write)
+		return
+	}
+	if len(content) == 0 {
+		fmt.Fprintf(w, "[ -e %s ] || %s\n", q, write)
+		return
+	}
+	fmt.Fprintf(w, "if [ ! -e %s ]; then\n%s\nfi\n", q, write)
+}
+
+// shellWrite writes content to the quoted path, as a heredoc when the
+// content ends in a newline (which a heredoc always adds).
+func shellWrite(quotedPath, content string) string {
+	if !strings.HasSuffix(content, "\n") {
+		return fmt.Sprintf("printf '%%s' %s > %s", shellQuote(content), quotedPath)
+	}
+	delim := heredocDelimiter(content)
+	return fmt.Sprintf("cat > %s <<'%s'\n%s%s", quotedPath, delim, content, delim)
+}
+
+type powerShellDialect struct{}
+
+// psQuote quotes s as a PowerShell single-quoted string.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (powerShellDialect) header(w io.Writer, plan *Plan) {
+	fmt.Fprintf(w, "# Generated by treeforge: creates %s\n$ErrorActionPreference = 'Stop'\n\n", commentSafe(scriptPath(plan.Base)))
+}
+
+func (powerShellDialect) mkdir(w io.Writer, path string) {
+	fmt.Fprintf(w, "New-Item -ItemType Directory -Force -Path %s | Out-Null\n", psQuote(path))
+}
+
+func (powerShellDialect) file(w io.Writer, path string, content []byte, force bool) {
+	q := psQuote(path)
+	write := fmt.Sprintf("New-Item -ItemType File -Force -Path %s | Out-Null", q)
+	if len(content) > 0 {
+		write = fmt.Sprintf("[IO.File]::WriteAllText([IO.Path]::Combine($PWD.Path, %s), %s)", q, psQuote(string(content)))
+	}
+	if force {
+		fmt.Fprintf(w, "%s\n", write)
+		return
+	}
+	fmt.Fprintf(w, "if (-not (Test-Path -LiteralPath %s)) { %s }\n", q, write)
+}
+
+type makefileDialect struct{}
+
+// makeEscape protects a recipe line from make's own $ expansion.
+func makeEscape(s string) string {
+	return strings.ReplaceAll(s, "$", "$$")
+}
+
+func (makefileDialect) header(w io.Writer, plan *Plan) {
+	fmt.Fprintf(w, "# Generated by treeforge: creates %s\n.PHONY: all\nall:\n", makeEscape(commentSafe(scriptPath(plan.Base))))
+}
+
+func (makefileDialect) mkdir(w io.Writer, path string) {
+	fmt.Fprintf(w, "\tmkdir -p %s\n", makeEscape(shellQuote(path)))
+}
+
+func (makefileDialect) file(w io.Writer, path string, content []byte, force bool) {
+	q := shellQuote(path)
+	write := ": > " + q
+	if len(content) > 0 {
+		write = fmt.Sprintf("printf %s > %s", shellQuote(printfEscape(string(content))), q)
+	}
+	if !force {
+		write = fmt.Sprintf("[ -e %s ] || %s", q, write)
+	}
+	fmt.Fprintf(w, "\t%s\n", makeEscape(write))
+}
@@ -0,0 +1,159 @@
+package treeforge
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func scriptTestPlan(force bool) *Plan {
+	tree := &Tree{
+		Root: "my app",
+		Entries: []Entry{
+			{Path: "src", Kind: KindDir},
+			{Path: "src/main.go", Kind: KindFile, Content: []byte("package main\n")},
+			{Path: "docs/it's.md", Kind: KindFile},
+			{Path: "notes.txt", Kind: KindFile, Content: []byte("100% $HOME \\n\nTREEFORGE_EOF\nno newline")},
+			{Path: "keep.txt", Kind: KindFile, Content: []byte("new\n")},
+		},
+	}
+	plan := NewPlan(tree, "")
+	plan.Force = force
+	return plan
+}
+
+func TestParseScriptFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected ScriptFormat
+		hasError bool
+	}{
+		{name: "sh", expected: ScriptShell},
+		{name: "PowerShell", expected: ScriptPowerShell},
+		{name: "makefile", expected: ScriptMakefile},
+		{name: "bat", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := ParseScriptFormat(tt.name)
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("ParseScriptFormat(%q) expected error", tt.name)
+				}
+				return
+			}
+			if err != nil || format != tt.expected {
+				t.Errorf("ParseScriptFormat(%q) = %v, %v; want %v", tt.name, format, err, tt.expected)
+			}
+		})
+	}
+}
+
+// runScript writes the script for plan into a fresh directory that already
+// holds "my app/keep.txt", runs it there and returns the directory.
+func runScript(t *testing.T, format ScriptFormat, name string, command ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath(command[0]); err != nil {
+		t.Skipf("%s not available", command[0])
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "my app"), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "my app", "keep.txt"), []byte("old\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteScript(&buf, scriptTestPlan(false), format); err != nil {
+		t.Fatalf("WriteScript() unexpected error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("running script failed: %v\n%s\nscript:\n%s", err, out, buf.String())
+	}
+	return dir
+}
+
+func checkScriptResult(t *testing.T, dir string) {
+	t.Helper()
+	want := map[string]string{
+		"my app/src/main.go":  "package main\n",
+		"my app/docs/it's.md": "",
+		"my app/notes.txt":    "100% $HOME \\n\nTREEFORGE_EOF\nno newline",
+		"my app/keep.txt":     "old\n",
+	}
+	for path, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("script did not create %s: %v", path, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", path, data, content)
+		}
+	}
+}
+
+func TestWriteScriptShell(t *testing.T) {
+	dir := runScript(t, ScriptShell, "create.sh", "sh", "create.sh")
+	checkScriptResult(t, dir)
+}
+
+func TestWriteScriptMakefile(t *testing.T) {
+	dir := runScript(t, ScriptMakefile, "Makefile", "make", "-s")
+	checkScriptResult(t, dir)
+}
+
+func TestWriteScriptPowerShell(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteScript(&buf, scriptTestPlan(false), ScriptPowerShell); err != nil {
+		t.Fatalf("WriteScript() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		"$ErrorActionPreference = 'Stop'",
+		"New-Item -ItemType Directory -Force -Path 'my app/src' | Out-Null",
+		"if (-not (Test-Path -LiteralPath 'my app/docs/it''s.md')) { New-Item -ItemType File -Force -Path 'my app/docs/it''s.md' | Out-Null }",
+		"[IO.File]::WriteAllText([IO.Path]::Combine($PWD.Path, 'my app/src/main.go'), 'package main\n')",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PowerShell script missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteScriptForce(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteScript(&buf, scriptTestPlan(true), ScriptShell); err != nil {
+		t.Fatalf("WriteScript() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "[ -e") || strings.Contains(out, "if [ ! -e") {
+		t.Errorf("forced script still checks for existing files:\n%s", out)
+	}
+	if !strings.Contains(out, "cat > 'my app/keep.txt' <<'TREEFORGE_EOF'\nnew\nTREEFORGE_EOF\n") {
+		t.Errorf("forced script does not overwrite keep.txt:\n%s", out)
+	}
+}
+
+func TestHeredocDelimiter(t *testing.T) {
+	if got := heredocDelimiter("plain\n"); got != "TREEFORGE_EOF" {
+		t.Errorf("heredocDelimiter() = %q, want TREEFORGE_EOF", got)
+	}
+	if got := heredocDelimiter("TREEFORGE_EOF\nTREEFORGE_EOF_1\n"); got != "TREEFORGE_EOF_2" {
+		t.Errorf("heredocDelimiter() = %q, want TREEFORGE_EOF_2", got)
+	}
+}
@@ -0,0 +1,176 @@
+package treeforge
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// EntrySource yields entries one at a time, returning io.EOF after the
+// last one.
+type EntrySource interface {
+	Next() (Entry, error)
+}
+
+// Parser reads a tree diagram incrementally. Memory use is bounded by the
+// depth of the tree and the longest line rather than the number of
+// entries, and lines may be of any length.
+//
+// Unlike Parse, a Parser hands out entries before it has seen the whole
+// diagram, so it reports a second top-level root as an error, and in
+// strict mode it stops at the first line with problems. Entries left out
+// by "# if:" conditions are dropped rather than kept as exclusions.
+type Parser struct {
+	r       *bufio.Reader
+	p       *parser
+	num     int
+	started bool
+	err     error
+}
+
+// NewParser returns a Parser reading from r.
+func NewParser(r io.Reader, opts Options) *Parser {
+	p := newParser(opts)
+	p.singleRoot = true
+	return &Parser{r: bufio.NewReader(r), p: p}
+}
+
+// Root returns the directory entries are relative to, as Tree.Root does
+// for Parse. It reads the first line if Next has not been called yet.
+func (ps *Parser) Root() (string, error) {
+	if err := ps.start(); err != nil {
+		return "", err
+	}
+	names := make([]string, len(ps.p.roots))
+	for i, r := range ps.p.roots {
+		names[i] = r.name
+	}
+	return determineRootName(ps.p.opts.RootName, names), nil
+}
+
+// Next returns the next entry, or io.EOF after the last one.
+func (ps *Parser) Next() (Entry, error) {
+	if err := ps.start(); err != nil {
+		return Entry{}, err
+	}
+	for len(ps.p.entries) == 0 {
+		if ps.err != nil {
+			return Entry{}, ps.err
+		}
+		line, err := ps.readLine()
+		if errors.Is(err, io.EOF) {
+			ps.p.checkBlocks()
+			if len(ps.p.issues) > 0 {
+				err = &ParseError{Issues: ps.p.issues}
+			}
+		}
+		if err != nil {
+			ps.err = err
+			continue
+		}
+		ps.add(line)
+		// Entries left out by conditions are not kept while streaming.
+		ps.p.excluded = ps.p.excluded[:0]
+	}
+
+	e := ps.p.entries[0]
+	ps.p.entries = ps.p.entries[1:]
+	if len(ps.p.entries) == 0 {
+		ps.p.entries = ps.p.entries[:0:0]
+	}
+	return e, nil
+}
+
+// start handles the first line, which may be a root rather than an entry.
+func (ps *Parser) start() error {
+	if ps.started {
+		return nil
+	}
+	ps.started = true
+
+	line, err := ps.readLine()
+	if errors.Is(err, io.EOF) {
+		err = errors.New("empty tree")
+	}
+	if err != nil {
+		ps.err = err
+		return err
+	}
+	isEntry, err := ps.p.begin(line)
+	if err != nil {
+		ps.err = err
+		return err
+	}
+	if isEntry {
+		ps.add(line)
+	}
+	return nil
+}
+
+// add parses one line and turns any problems into the parser's error.
+func (ps *Parser) add(line string) {
+	ps.p.add(ps.num, line)
+	switch {
+	case ps.p.err != nil:
+		ps.err = ps.p.err
+	case len(ps.p.issues) > 0:
+		ps.err = &ParseError{Issues: ps.p.issues}
+	default:
+		return
+	}
+	ps.p.entries = ps.p.entries[:0]
+}
+
+// readLine returns the next line without its line ending, however long.
+func (ps *Parser) readLine() (string, error) {
+	line, err := ps.r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	ps.num++
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+// ApplyStream creates entries from src on fsys as they are read, without
+// building the whole tree first. Only plan.Base and the plan's conflict
+// settings (Force, OnConflict and Ask) are used.
+// fn, if non-nil, is called with each result; an error from it stops the
+// run and is returned.
+func ApplyStream(ctx context.Context, plan *Plan, src EntrySource, fsys FS, fn func(Result) error) error {
+	if err := fsys.MkdirAll(plan.Base, 0755); err != nil {
+		return fmt.Errorf("creating base directory: %w", err)
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		entry, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		step := Step{Entry: entry, Target: filepath.Join(plan.Base, entry.Path)}
+		res, err := createEntry(ctx, fsys, step, plan)
+		if err != nil {
+			return err
+		}
+		if fn != nil {
+			if err := fn(res); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package treeforge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// collect drains ps, returning its entries.
+func collect(t *testing.T, ps *Parser) []Entry {
+	t.Helper()
+	var entries []Entry
+	for {
+		e, err := ps.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Next() unexpected error: %v", err)
+		}
+		entries = append(entries, e)
+	}
+}
+
+func TestParserMatchesParse(t *testing.T) {
+	inputs := []string{
+		"myapp/\n├─ src/\n│  ├─ handlers/\n│  │  └─ user.go\n│  └─ main.go\n└─ README.md\n",
+		"myapp/\r\n├─ src/\r\n│  └─ main.go\r\n",
+		"├─ src/\n│  └─ main.go\n└─ README.md",
+		".\n├── cmd/\n│   └── main.go # entry\n\n└── go.mod",
+		"only-root/",
+	}
+
+	for _, input := range inputs {
+		want, err := Parse(strings.NewReader(input), Options{})
+		if err != nil {
+			t.Fatalf("Parse(%q) unexpected error: %v", input, err)
+		}
+
+		ps := NewParser(strings.NewReader(input), Options{})
+		root, err := ps.Root()
+		if err != nil {
+			t.Fatalf("Root() unexpected error: %v", err)
+		}
+		if root != want.Root {
+			t.Errorf("Root() = %q, want %q", root, want.Root)
+		}
+		if got := collect(t, ps); !reflect.DeepEqual(got, want.Entries) && len(got)+len(want.Entries) > 0 {
+			t.Errorf("Parser entries for %q mismatch:\n%s", input, cmpEntries(want.Entries, got))
+		}
+	}
+}
+
+func TestParserLongLines(t *testing.T) {
+	long := strings.Repeat("x", 200*1024)
+	input := "root/\n├─ " + long + "/\n│  └─ " + long + ".txt\n"
+
+	got := collect(t, NewParser(strings.NewReader(input), Options{}))
+	if len(got) != 2 || got[1].Path != long+"/"+long+".txt" {
+		t.Errorf("Parser did not parse %d-byte lines", len(long))
+	}
+
+	tree, err := Parse(strings.NewReader(input), Options{})
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if len(tree.Entries) != 2 {
+		t.Errorf("Parse() got %d entries, want 2", len(tree.Entries))
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  Options
+		want  string
+	}{
+		{name: "empty input", input: "", want: "empty tree"},
+		{name: "blank root", input: "\n├─ a\n", want: "invalid root line"},
+		{name: "second root", input: "a/\n└─ x\nb/\n└─ y\n", want: "line 3: b/ starts a second top-level root"},
+		{name: "strict", input: "a/\n├─ x/\n│  │  │  └─ y\n", opts: Options{Strict: true}, want: "line 3: y: indented to level 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := NewParser(strings.NewReader(tt.input), tt.opts)
+			var err error
+			for err == nil {
+				_, err = ps.Next()
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Next() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyStream(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("big/\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&b, "├─ dir%d/\n│  └─ file.txt\n", i)
+	}
+
+	ps := NewParser(strings.NewReader(b.String()), Options{})
+	root, err := ps.Root()
+	if err != nil {
+		t.Fatalf("Root() unexpected error: %v", err)
+	}
+
+	fsys := NewMemFS()
+	plan := NewPlan(&Tree{Root: root}, "out")
+	created := 0
+	err = ApplyStream(context.Background(), plan, ps, fsys, func(res Result) error {
+		if res.Status == StatusCreated {
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ApplyStream() unexpected error: %v", err)
+	}
+	if created != 200 {
+		t.Errorf("ApplyStream() created %d entries, want 200", created)
+	}
+	if _, err := fsys.Stat("out/big/dir99/file.txt"); err != nil {
+		t.Errorf("ApplyStream() did not create dir99/file.txt: %v", err)
+	}
+
+	// An error from the callback stops the run.
+	stop := errors.New("stop")
+	ps = NewParser(strings.NewReader(b.String()), Options{})
+	calls := 0
+	err = ApplyStream(context.Background(), plan, ps, NewMemFS(), func(Result) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("ApplyStream() = %v after %d calls, want stop after 1", err, calls)
+	}
+}
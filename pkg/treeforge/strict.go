@@ -0,0 +1,76 @@
+package treeforge
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ParseError lists every problem found by a strict parse.
+type ParseError struct {
+	Issues []Issue
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Issues))
+	for i, is := range e.Issues {
+		msgs[i] = is.String()
+	}
+	return fmt.Sprintf("%d problem(s) in tree:\n  %s", len(e.Issues), strings.Join(msgs, "\n  "))
+}
+
+// checkStrict records the problems with line l, numbered num, that the
+// forgiving parser would otherwise paper over.
+func (p *parser) checkStrict(num int, path string, l parsedLine) {
+	report := func(format string, args ...any) {
+		p.issues = append(p.issues, Issue{Path: path, Line: num, File: p.file, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch {
+	case l.level > p.maxLevel && l.level == p.maxLevel+1 && p.lastFile != "":
+		report("indented under %s, which is a file; add a trailing / to make it a directory", p.lastFile)
+	case l.level > p.maxLevel:
+		report("indented to level %d, but the line above only opens level %d", l.level, p.maxLevel)
+	}
+	p.maxLevel, p.lastFile = l.level, path
+	if l.isDir {
+		p.maxLevel, p.lastFile = l.level+1, ""
+	}
+
+	col := utf8.RuneCountInString(l.prefix)
+	if want, ok := p.columns[l.level]; !ok {
+		p.columns[l.level] = col
+	} else if col != want {
+		report("name starts in column %d, but other entries at this level start in column %d", col+1, want+1)
+	}
+
+	if r, ok := unknownGlyph(l.name); ok {
+		report("unknown glyph %q", r)
+	}
+	if run := strippedRun(l.prefix); run != "" {
+		report("name follows %q without a space, so leading characters may have been stripped", run)
+	}
+}
+
+// unknownGlyph finds drawing characters the parser does not understand,
+// such as heavy or double box-drawing lines, left in a name.
+func unknownGlyph(name string) (rune, bool) {
+	for _, r := range name {
+		if r >= '\u2500' && r <= '\u259F' { // box drawing and block elements
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// strippedRun returns the decoration glued to the front of a name, which
+// may have been part of the name: in "├─-foo" the "-" is lost.
+func strippedRun(prefix string) string {
+	i := strings.LastIndexFunc(prefix, unicode.IsSpace)
+	if i < 0 {
+		return prefix
+	}
+	_, size := utf8.DecodeRuneInString(prefix[i:])
+	return prefix[i+size:]
+}
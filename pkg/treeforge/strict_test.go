@@ -0,0 +1,124 @@
+package treeforge
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStrict(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string // substrings of the expected issues, in order
+	}{
+		{
+			name: "unicode box drawing",
+			lines: []string{
+				"myapp/",
+				"├─ src/",
+				"│  ├─ handlers/",
+				"│  │  └─ user.go",
+				"│  └─ main.go",
+				"└─ README.md",
+			},
+		},
+		{
+			name: "tree -F output",
+			lines: []string{
+				".",
+				"├── src/",
+				"│   └── main.go",
+				"└── go.mod",
+			},
+		},
+		{
+			name: "tab indentation",
+			lines: []string{
+				"myapp/",
+				"src/",
+				"\tmain.go",
+				"README.md",
+			},
+		},
+		{
+			name: "inconsistent indentation",
+			lines: []string{
+				"myapp/",
+				"├─ src/",
+				"│  ├─ a.go",
+				"│   └─ b.go",
+			},
+			want: []string{"line 4: src/b.go: name starts in column 8, but other entries at this level start in column 7"},
+		},
+		{
+			name: "level jump",
+			lines: []string{
+				"myapp/",
+				"├─ src/",
+				"│  │  │  └─ deep.go",
+			},
+			want: []string{"line 3: deep.go: indented to level 3, but the line above only opens level 1"},
+		},
+		{
+			name: "indented under a file",
+			lines: []string{
+				"myapp/",
+				"├─ main.go",
+				"│  └─ extra.go",
+			},
+			want: []string{"line 3: extra.go: indented under main.go, which is a file"},
+		},
+		{
+			name: "unknown glyph",
+			lines: []string{
+				"myapp/",
+				"┣━ src/",
+			},
+			want: []string{"unknown glyph '┣'"},
+		},
+		{
+			name: "stripped leading dash",
+			lines: []string{
+				"myapp/",
+				"├─ -flags.txt",
+				"└──draft.md",
+			},
+			want: []string{
+				`flags.txt: name follows "-"`,
+				"draft.md: name starts in column 4",
+				`draft.md: name follows "└──"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseLines(tt.lines, Options{Strict: true})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("ParseLines() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("ParseLines() error = %v, want *ParseError", err)
+			}
+			if len(perr.Issues) != len(tt.want) {
+				t.Fatalf("ParseLines() reported %d issues, want %d:\n%v", len(perr.Issues), len(tt.want), err)
+			}
+			for i, want := range tt.want {
+				if got := perr.Issues[i].String(); !strings.Contains(got, want) {
+					t.Errorf("issue %d = %q, want it to contain %q", i, got, want)
+				}
+			}
+
+			// The forgiving parser accepts the same input.
+			if _, err := ParseLines(tt.lines, Options{}); err != nil {
+				t.Errorf("ParseLines() without Strict: unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// Package treeforge turns folder tree diagrams, as printed by tree(1) or
+// pasted from chat and markdown, into directories and files.
+//
+// A diagram is parsed into a Tree, turned into a Plan rooted at a parent
+// directory, and applied to an FS:
+//
+//	tree, err := treeforge.Parse(r, treeforge.Options{})
+//	plan := treeforge.NewPlan(tree, "/path/to/parent")
+//	report, err := treeforge.Apply(ctx, plan, treeforge.OSFS{})
+package treeforge
+
+import "fmt"
+
+type Kind int
+
+const (
+	KindDir Kind = iota
+	KindFile
+)
+
+func (k Kind) String() string {
+	if k == KindDir {
+		return "dir"
+	}
+	return "file"
+}
+
+// Entry is a single directory or file, with Path relative to the tree root.
+type Entry struct {
+	Path string
+	Kind Kind
+
+	// Line is the 1-based input line the entry came from, or 0.
+	Line int
+
+	// File is the fragment the entry's line is in, as passed to
+	// Options.Include, or empty for the main input.
+	File string
+
+	// Original is the name as written in the input when normalization
+	// changed it, and empty otherwise.
+	Original string
+
+	// Content is written to a file entry when it is created. The parser
+	// leaves it empty; callers may fill it in before planning.
+	Content []byte
+}
+
+// Tree is the parsed form of a tree diagram.
+type Tree struct {
+	// Root is the name of the top-level directory.
+	Root    string
+	Entries []Entry
+
+	// Excluded holds entries that were filtered out, and why.
+	Excluded []Exclusion
+}
+
+// position describes where a line is for messages: "line 3", or
+// "line 3 of ci.tree" inside an included fragment.
+func position(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("line %d of %s", line, file)
+}
+
+// Exclusion is an entry left out of a tree together with the reason.
+type Exclusion struct {
+	Entry  Entry
+	Reason string
+}
@@ -0,0 +1,115 @@
+package treeforge
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxPortablePath is the classic Windows MAX_PATH, counting the root.
+	maxPortablePath = 260
+	// maxPortableName is the per-component limit on common filesystems.
+	maxPortableName = 255
+)
+
+// Issue is a problem found in a tree, attached to the entry it concerns.
+type Issue struct {
+	Path    string
+	Line    int    // input line of the entry, or 0 if unknown
+	File    string // included fragment the line is in, or empty
+	Message string
+}
+
+func (i Issue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s: %s: %s", position(i.File, i.Line), i.Path, i.Message)
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Message)
+}
+
+// windowsReserved are device names Windows refuses as a file or directory
+// name, with or without an extension.
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// CheckPortable reports names that would not survive on every common
+// platform: Windows reserved names and characters, trailing dots and
+// spaces, over-long names and paths, and entries that differ only in case
+// and so collide on case-insensitive filesystems such as macOS and Windows.
+func (t *Tree) CheckPortable() []Issue {
+	var issues []Issue
+	seen := map[string]string{}
+
+	for _, e := range t.Entries {
+		name := filepath.Base(e.Path)
+		for _, msg := range checkPortableName(name) {
+			issues = append(issues, Issue{Path: e.Path, Line: e.Line, File: e.File, Message: msg})
+		}
+
+		if n := utf8.RuneCountInString(t.Root) + 1 + utf8.RuneCountInString(e.Path); n >= maxPortablePath {
+			issues = append(issues, Issue{Path: e.Path, Line: e.Line, File: e.File, Message: fmt.Sprintf("path is %d characters long; Windows allows %d", n, maxPortablePath-1)})
+		}
+
+		key := strings.ToLower(e.Path)
+		if other, ok := seen[key]; ok && other != e.Path {
+			issues = append(issues, Issue{Path: e.Path, Line: e.Line, File: e.File, Message: fmt.Sprintf("differs from %s only in case", other)})
+		} else if !ok {
+			seen[key] = e.Path
+		}
+	}
+	return issues
+}
+
+func checkPortableName(name string) []string {
+	var msgs []string
+
+	stem := strings.ToUpper(name)
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	if windowsReserved[strings.TrimRight(stem, " ")] {
+		msgs = append(msgs, fmt.Sprintf("%q is a reserved name on Windows", name))
+	}
+
+	if bad := illegalChars(name); bad != "" {
+		msgs = append(msgs, fmt.Sprintf("contains characters not allowed on Windows: %s", bad))
+	}
+
+	if strings.HasSuffix(name, ".") || strings.HasSuffix(name, " ") {
+		msgs = append(msgs, "ends with a dot or space, which Windows drops")
+	}
+
+	if len(name) > maxPortableName {
+		msgs = append(msgs, fmt.Sprintf("name is %d bytes long; most filesystems allow %d", len(name), maxPortableName))
+	}
+	return msgs
+}
+
+// illegalChars lists, quoted and sorted, the characters in name that
+// Windows does not accept in file names.
+func illegalChars(name string) string {
+	found := map[rune]bool{}
+	for _, r := range name {
+		if r < 0x20 || strings.ContainsRune(`<>:"\|?*`, r) {
+			found[r] = true
+		}
+	}
+	if len(found) == 0 {
+		return ""
+	}
+
+	chars := make([]string, 0, len(found))
+	for r := range found {
+		chars = append(chars, fmt.Sprintf("%q", r))
+	}
+	sort.Strings(chars)
+	return strings.Join(chars, " ")
+}
@@ -0,0 +1,80 @@
+package treeforge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPortable(t *testing.T) {
+	long := strings.Repeat("a", 256)
+	deep := strings.Repeat("d/", 130) + "f"
+
+	tests := []struct {
+		name    string
+		entries []Entry
+		want    []string // substrings of the expected messages, in order
+	}{
+		{
+			name: "portable names",
+			entries: []Entry{
+				{Path: "src", Kind: KindDir},
+				{Path: "src/main.go", Kind: KindFile},
+				{Path: "console.go", Kind: KindFile},
+			},
+		},
+		{
+			name: "reserved names",
+			entries: []Entry{
+				{Path: "aux.go", Kind: KindFile},
+				{Path: "con", Kind: KindDir},
+				{Path: "src/LPT1", Kind: KindFile},
+			},
+			want: []string{`"aux.go" is a reserved name`, `"con" is a reserved name`, `"LPT1" is a reserved name`},
+		},
+		{
+			name: "illegal characters",
+			entries: []Entry{
+				{Path: "what?.md", Kind: KindFile},
+				{Path: "a:b<c>.txt", Kind: KindFile},
+			},
+			want: []string{`'?'`, `':' '<' '>'`},
+		},
+		{
+			name: "trailing dot and space",
+			entries: []Entry{
+				{Path: "notes.", Kind: KindFile},
+				{Path: "dir ", Kind: KindDir},
+			},
+			want: []string{"ends with a dot or space", "ends with a dot or space"},
+		},
+		{
+			name:    "long name and path",
+			entries: []Entry{{Path: long, Kind: KindFile}, {Path: deep, Kind: KindFile}},
+			want:    []string{"name is 256 bytes long", "path is 262 characters long", "path is 267 characters long"},
+		},
+		{
+			name: "case collision",
+			entries: []Entry{
+				{Path: "README.md", Kind: KindFile},
+				{Path: "readme.md", Kind: KindFile},
+				{Path: "Readme.md", Kind: KindFile},
+			},
+			want: []string{"differs from README.md only in case", "differs from README.md only in case"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := &Tree{Root: "myapp", Entries: tt.entries}
+			issues := tree.CheckPortable()
+			if len(issues) != len(tt.want) {
+				t.Fatalf("CheckPortable() = %v, want %d issues", issues, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(issues[i].Message, want) {
+					t.Errorf("issue %d = %q, want it to contain %q", i, issues[i].Message, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// runStream parses and creates the input one entry at a time, so trees
+// with hundreds of thousands of entries never sit in memory. Checks that
+// need the whole tree (duplicates, portability, .gitkeep) are skipped.
+func runStream(ctx context.Context, flags *cliFlags, cfg *Config, opts treeforge.Options) error {
+	if flags.archive != "" || flags.emit != "" || cfg.Git || flags.rollback || flags.resume || flags.interactive {
+		return errors.New("--stream cannot be combined with --output-archive, --emit, --git, --rollback, --resume or --interactive")
+	}
+	if _, _, ok := packedInput(flags.inputFile); ok {
+		return errors.New("--stream reads plain tree files, not git repositories or archives")
+	}
+
+	in, err := openInput(flags.inputFile, cfg.Verbose)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	ps := treeforge.NewParser(in, opts)
+	root, err := ps.Root()
+	if err != nil {
+		return err
+	}
+	plan := treeforge.NewPlan(&treeforge.Tree{Root: root}, cfg.Parent)
+	plan.Force = cfg.Force
+	if err := setConflicts(plan, cfg, newConflictPrompt(ctx, flags.apply)); err != nil {
+		return err
+	}
+
+	ig, err := loadIgnore(plan.Base, cfg.Exclude, flags.noIgnore)
+	if err != nil {
+		return err
+	}
+	src := &excludeSource{src: ps, ig: ig}
+
+	if !flags.apply {
+		return streamDryRun(plan, src)
+	}
+	if err := checkContentDir(cfg); err != nil {
+		return err
+	}
+	var entries treeforge.EntrySource = src
+	if cfg.ContentDir != "" {
+		entries = contentSource{src: src, dir: cfg.ContentDir, vars: templateVars(cfg.Vars)}
+	}
+	return streamApply(ctx, plan, entries, enabledHooks(cfg, flags), cfg.Verbose)
+}
+
+// streamDryRun lists what a streaming apply would do, checking only
+// whether each file already exists.
+func streamDryRun(plan *treeforge.Plan, src *excludeSource) error {
+	fmt.Println("=== Dry-run mode (use --apply to create files) ===")
+	fmt.Printf("Base: %s\n\n", plan.Base)
+
+	dirs, files := 0, 0
+	err := forEachEntry(src, func(e treeforge.Entry) {
+		step := treeforge.Step{Entry: e, Target: filepath.Join(plan.Base, e.Path)}
+		status := treeforge.StatusCreated
+		if e.Kind == treeforge.KindDir {
+			dirs++
+		} else {
+			files++
+			if _, err := os.Stat(step.Target); err == nil {
+				status = existingStatus(plan)
+			}
+		}
+		printResult(treeforge.Result{Step: step, Status: status})
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nTotal: %d directories, %d files", dirs, files)
+	if src.excluded > 0 {
+		fmt.Printf(", %d excluded", src.excluded)
+	}
+	fmt.Println()
+	return nil
+}
+
+// streamApply creates the entries from src as they arrive. Only the
+// results that per-entry hooks run for are kept.
+func streamApply(ctx context.Context, plan *treeforge.Plan, src treeforge.EntrySource, hooks []Hook, verbose bool) error {
+	counts := map[treeforge.Status]int{}
+	count := func(st treeforge.Status) int { return counts[st] }
+	created := 0
+	var hooked []treeforge.Result
+	err := treeforge.ApplyStream(ctx, plan, src, treeforge.OSFS{}, func(res treeforge.Result) error {
+		counts[res.Status]++
+		if written(res.Status) {
+			created++
+		}
+		if wantsResult(hooks, res) {
+			hooked = append(hooked, res)
+		}
+		if verbose || needsAttention(res.Status) {
+			printResult(res)
+		}
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("\n⚠ Interrupted. %s\n", summary(count))
+		return errors.New("interrupted")
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n✓ Done! %s\n", summary(count))
+	return afterApply(ctx, hooks, plan, hooked, created, verbose)
+}
+
+// existingStatus is what a streaming apply will do with a file that
+// already exists.
+func existingStatus(plan *treeforge.Plan) treeforge.Status {
+	switch {
+	case plan.Force || plan.OnConflict == treeforge.ConflictOverwrite:
+		return treeforge.StatusOverwritten
+	case plan.OnConflict == treeforge.ConflictBackup:
+		return treeforge.StatusBackedUp
+	case plan.OnConflict == treeforge.ConflictRename:
+		return treeforge.StatusRenamed
+	case plan.OnConflict == treeforge.ConflictMerge:
+		return treeforge.StatusMerged
+	}
+	return treeforge.StatusSkipped
+}
+
+// forEachEntry calls fn with every entry from src.
+func forEachEntry(src treeforge.EntrySource, fn func(treeforge.Entry)) error {
+	for {
+		e, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(e)
+	}
+}
+
+// excludeSource passes on the entries from src that ig does not match,
+// counting the rest.
+type excludeSource struct {
+	src      treeforge.EntrySource
+	ig       *treeforge.Ignore
+	excluded int
+}
+
+func (s *excludeSource) Next() (treeforge.Entry, error) {
+	for {
+		e, err := s.src.Next()
+		if err != nil || !s.ig.Match(e.Path, e.Kind == treeforge.KindDir) {
+			return e, err
+		}
+		s.excluded++
+	}
+}
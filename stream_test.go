@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+func TestRunStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	input := filepath.Join(tmpDir, "tree.txt")
+	content := "myapp/\n├─ src/\n│  └─ main.go\n├─ debug.log\n└─ README.md\n"
+	if err := os.WriteFile(input, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write input: %v", err)
+	}
+
+	cfg := defaultConfig()
+	cfg.Parent = tmpDir
+	cfg.Exclude = []string{"*.log"}
+	flags := &cliFlags{inputFile: input, stream: true}
+
+	if err := runStream(context.Background(), flags, cfg, treeforge.Options{}); err != nil {
+		t.Fatalf("runStream() dry-run unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp")); !os.IsNotExist(err) {
+		t.Errorf("runStream() dry-run created myapp")
+	}
+
+	flags.apply = true
+	if err := runStream(context.Background(), flags, cfg, treeforge.Options{}); err != nil {
+		t.Fatalf("runStream() unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp", "src", "main.go")); err != nil {
+		t.Errorf("runStream() did not create main.go: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "myapp", "debug.log")); !os.IsNotExist(err) {
+		t.Errorf("runStream() created the excluded debug.log")
+	}
+
+	cfg.Git = true
+	if err := runStream(context.Background(), flags, cfg, treeforge.Options{}); err == nil || !strings.Contains(err.Error(), "--git") {
+		t.Errorf("runStream() with --git: error = %v, want it to mention --git", err)
+	}
+}
@@ -2,11 +2,18 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strings"
+	"syscall"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
 )
 
 var (
@@ -15,191 +22,497 @@ var (
 
 // Helper functions for main
 func processInput(inputFile string, verbose bool) ([]string, error) {
-	if inputFile != "" {
-		return readFromFile(inputFile, verbose)
+	in, err := openInput(inputFile, verbose)
+	if err != nil {
+		return nil, err
 	}
-	return readFromStdin(verbose)
+	defer in.Close()
+	return readLines(in)
 }
 
-func determineRootName(rootName, firstLine string) string {
-	if rootName != "" {
-		return rootName
+// printDryRun applies the plan to a recording filesystem layered over the
+// real one, so existing files show up as skipped exactly as with --apply,
+// and lists the hooks that apply would run.
+func printDryRun(plan *treeforge.Plan, hooks []Hook) error {
+	report, err := treeforge.Apply(context.Background(), plan, treeforge.NewDryRunFS(treeforge.OSFS{}))
+	if err != nil {
+		return err
 	}
 
-	root := strings.TrimSpace(firstLine)
-	root = strings.TrimSuffix(root, "/")
-	if root == "" {
-		return "output"
+	fmt.Println("=== Dry-run mode (use --apply to create files) ===")
+	fmt.Printf("Base: %s\n\n", plan.Base)
+	for _, res := range report.Results {
+		printResult(res)
 	}
-	return root
+	printExcluded(plan)
+	printHookPlan(hooks, report.Results)
+	if plan.OnConflict == treeforge.ConflictAsk {
+		fmt.Println("\nExisting files are shown as skipped; --apply will ask about each one.")
+	}
+
+	fmt.Printf("\nTotal: %d directories, %d files", plan.Count(treeforge.KindDir), plan.Count(treeforge.KindFile))
+	if n := len(plan.Excluded); n > 0 {
+		fmt.Printf(", %d excluded", n)
+	}
+	fmt.Println()
+	return nil
 }
 
-func printDryRun(basePath string, entries []Entry) {
-	fmt.Println("=== Dry-run mode (use --apply to create files) ===")
-	fmt.Printf("Base: %s\n\n", basePath)
-	for _, entry := range entries {
-		fullPath := filepath.Join(basePath, entry.Path)
-		if entry.Kind == KindDir {
-			fmt.Printf("  [DIR]  %s\n", fullPath)
-		} else {
-			fmt.Printf("  [FILE] %s\n", fullPath)
-		}
+func printResult(res treeforge.Result) {
+	switch {
+	case res.Status == treeforge.StatusSkipped:
+		fmt.Printf("  [SKIP] %s (already exists)%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusOverwritten:
+		fmt.Printf("  [OVER] %s%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusBackedUp:
+		fmt.Printf("  [BAK]  %s (old contents saved as %s)%s\n", res.Step.Target, orUnknown(res.Path, "a .bak file"), normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusUnchanged:
+		fmt.Printf("  [SAME] %s (already has this content)%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusFilled:
+		fmt.Printf("  [FILL] %s (was empty)%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusDiffers:
+		fmt.Printf("  [DIFF] %s (has other content; kept)%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusMerged:
+		fmt.Printf("  [MERG] %s (conflict markers added)%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Status == treeforge.StatusRenamed:
+		fmt.Printf("  [REN]  %s (%s exists)%s\n", orUnknown(res.Path, "numbered name"), res.Step.Target, normalizedNote(res.Step.Entry))
+	case res.Step.Entry.Kind == treeforge.KindDir:
+		fmt.Printf("  [DIR]  %s%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
+	default:
+		fmt.Printf("  [FILE] %s%s\n", res.Step.Target, normalizedNote(res.Step.Entry))
 	}
-	fmt.Printf("\nTotal: %d directories, %d files\n", countDirs(entries), countFiles(entries))
 }
 
-func createEntry(entry Entry, basePath string, force, verbose bool) (string, error) {
-	fullPath := filepath.Join(basePath, entry.Path)
+// orUnknown returns path, or a description of it when a streaming dry
+// run could not work it out.
+func orUnknown(path, what string) string {
+	if path == "" {
+		return "<" + what + ">"
+	}
+	return path
+}
 
-	if entry.Kind == KindDir {
-		if err := os.MkdirAll(fullPath, 0755); err != nil {
-			return "", fmt.Errorf("creating directory %s: %w", fullPath, err)
-		}
-		if verbose {
-			fmt.Printf("  [DIR]  %s\n", fullPath)
-		}
-		return "created", nil
-	} else {
-		// Check if file exists
-		if _, err := os.Stat(fullPath); err == nil && !force {
-			if verbose {
-				fmt.Printf("  [SKIP] %s (already exists)\n", fullPath)
+// normalizedNote points out names that Unicode normalization changed,
+// quoting the original so invisible characters show up.
+func normalizedNote(e treeforge.Entry) string {
+	if e.Original == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (normalized from %+q)", e.Original)
+}
+
+func printExcluded(plan *treeforge.Plan) {
+	for _, ex := range plan.Excluded {
+		fmt.Printf("  [EXCL] %s (%s)\n", ex.Step.Target, ex.Reason)
+	}
+}
+
+// applyEntries creates the plan on disk. If the run is interrupted or
+// fails part-way, it reports how far it got and, with rollback, removes
+// what it had created.
+func applyEntries(ctx context.Context, plan *treeforge.Plan, verbose, rollback bool) (*treeforge.Report, error) {
+	var fsys treeforge.FS = treeforge.OSFS{}
+	var journal *treeforge.JournalFS
+	if rollback {
+		journal = treeforge.NewJournalFS(fsys)
+		fsys = journal
+	}
+
+	if verbose {
+		// Print each result as its entry is created, ahead of any
+		// callback already set, such as a checkpoint's.
+		next := plan.OnResult
+		plan.OnResult = func(res treeforge.Result) {
+			printResult(res)
+			if next != nil {
+				next(res)
 			}
-			return "skipped", nil
 		}
+		defer func() { plan.OnResult = next }()
+	}
 
-		// Create parent directory if needed
-		dir := filepath.Dir(fullPath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return "", fmt.Errorf("creating directory for file %s: %w", fullPath, err)
-		}
+	report, err := treeforge.Apply(ctx, plan, fsys)
+	if verbose {
+		printExcluded(plan)
+	}
+	if err != nil {
+		return report, stopApply(report, plan, journal, err)
+	}
 
-		// Create empty file
-		file, err := os.Create(fullPath)
-		if err != nil {
-			return "", fmt.Errorf("creating file %s: %w", fullPath, err)
+	fmt.Printf("\n✓ Done! %s\n", summary(report.Count))
+	if !verbose {
+		printConflictPaths(os.Stdout, report.Results)
+	}
+	return report, nil
+}
+
+// applyCheckpointed runs applyEntries while recording a checkpoint, which
+// is kept only if the run stops part-way and leaves its work in place. A
+// checkpoint that cannot be written does not stop the apply.
+func applyCheckpointed(ctx context.Context, plan *treeforge.Plan, flags *cliFlags, verbose bool, key string) (*treeforge.Report, error) {
+	cp, err := startCheckpoint(plan, key, flags.resume)
+	if err != nil {
+		if flags.resume {
+			return nil, err
 		}
-		file.Close()
+		fmt.Fprintf(os.Stderr, "Warning: not recording a checkpoint: %v\n", err)
+	}
 
-		if verbose {
-			fmt.Printf("  [FILE] %s\n", fullPath)
+	report, err := applyEntries(ctx, plan, verbose, flags.rollback)
+	if cp != nil {
+		if cerr := cp.finish(err != nil && !flags.rollback); cerr != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", cerr)
 		}
-		return "created", nil
 	}
+	return report, err
 }
 
-func applyEntries(basePath string, entries []Entry, force, verbose bool) error {
-	// Create base directory
-	if err := os.MkdirAll(basePath, 0755); err != nil {
-		return fmt.Errorf("creating base directory: %w", err)
+// stopApply reports a run that ended early and rolls it back if journal
+// is set.
+func stopApply(report *treeforge.Report, plan *treeforge.Plan, journal *treeforge.JournalFS, err error) error {
+	what := "Stopped"
+	if errors.Is(err, context.Canceled) {
+		what, err = "Interrupted", errors.New("interrupted")
 	}
+	fmt.Printf("\n⚠ %s after %d of %d entries. %s\n", what, len(report.Results), len(plan.Steps), summary(report.Count))
 
-	created := 0
-	skipped := 0
+	if journal == nil {
+		fmt.Println("The partial tree was left in place; run the same command with --resume to finish it.")
+		return err
+	}
+	removed, rerr := journal.Rollback()
+	fmt.Printf("↩ Rolled back: removed %d created paths\n", removed)
+	if rerr != nil {
+		return errors.Join(err, fmt.Errorf("rollback: %w", rerr))
+	}
+	return err
+}
 
-	for _, entry := range entries {
-		result, err := createEntry(entry, basePath, force, verbose)
-		if err != nil {
-			return err
+// writeArchive packs the tree into an archive rooted at the tree's root
+// directory. Nothing but the archive file itself is written.
+func writeArchive(tree *treeforge.Tree, path string, verbose bool) (err error) {
+	format, err := treeforge.ArchiveFormatFromName(path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
 		}
+	}()
 
-		if result == "created" {
-			created++
-		} else if result == "skipped" {
-			skipped++
+	plan := treeforge.NewPlan(tree, "")
+	afs := treeforge.NewArchiveFS(file, format)
+	report, err := treeforge.Apply(context.Background(), plan, afs)
+	if verbose {
+		for _, res := range report.Results {
+			printResult(res)
 		}
 	}
+	if err != nil {
+		return err
+	}
+	if err := afs.Close(); err != nil {
+		return fmt.Errorf("writing archive %s: %w", path, err)
+	}
 
-	fmt.Printf("\n✓ Done! Created: %d, Skipped: %d\n", created, skipped)
+	fmt.Printf("\n✓ Wrote %s: %d directories, %d files\n", path, plan.Count(treeforge.KindDir), plan.Count(treeforge.KindFile))
 	return nil
 }
 
+// cliFlags holds the flags that are not backed by a config key.
+type cliFlags struct {
+	inputFile   string
+	apply       bool
+	archive     string
+	emit        string
+	noIgnore    bool
+	stream      bool
+	rollback    bool
+	resume      bool
+	interactive bool
+	watch       bool
+	prune       bool
+	noHooks     bool
+	showVer     bool
+}
+
+func defineFlags(fs *flag.FlagSet) *cliFlags {
+	f := &cliFlags{}
+	fs.StringVar(&f.inputFile, "i", "", "Input tree structure file (default: stdin)")
+	fs.BoolVar(&f.apply, "apply", false, "Actually create files/directories (default: dry-run)")
+	fs.StringVar(&f.archive, "output-archive", "", "Write the structure to a .tar, .tar.gz or .zip file instead of disk")
+	fs.StringVar(&f.emit, "emit", "", "Print an equivalent script instead of creating files: sh, powershell or makefile")
+	fs.BoolVar(&f.noIgnore, "no-ignore", false, "Do not read .gitignore and .treeforgeignore files in the target")
+	fs.BoolVar(&f.stream, "stream", false, "Parse and create entries one at a time, for very large trees (skips whole-tree checks)")
+	fs.BoolVar(&f.rollback, "rollback", false, "If --apply is interrupted or fails, remove what it created")
+	fs.BoolVar(&f.resume, "resume", false, "Continue an --apply that was interrupted or failed, skipping the entries it finished")
+	fs.BoolVar(&f.interactive, "interactive", false, "Review the tree in a terminal UI to deselect or rename entries, then apply on confirmation")
+	fs.BoolVar(&f.watch, "watch", false, "Re-read the input file whenever it changes and create the entries added to it")
+	fs.BoolVar(&f.prune, "prune", false, "With --watch, remove entries dropped from the file if this watch created them and they are unchanged")
+	fs.BoolVar(&f.noHooks, "no-hooks", false, "Do not run the hooks from config files")
+	fs.BoolVar(&f.showVer, "version", false, "Show version")
+	return f
+}
+
+// exitIf reports err under msg and exits when err is non-nil.
+func exitIf(err error, msg string) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", msg, err)
+		os.Exit(1)
+	}
+}
+
 func main() {
-	var (
-		inputFile = flag.String("i", "", "Input tree structure file (default: stdin)")
-		parent    = flag.String("parent", ".", "Parent directory to create structure in")
-		rootName  = flag.String("root-name", "", "Override root directory name (from first line if empty)")
-		apply     = flag.Bool("apply", false, "Actually create files/directories (default: dry-run)")
-		force     = flag.Bool("force", false, "Overwrite existing files (directories are not deleted)")
-		verbose   = flag.Bool("v", false, "Verbose output")
-		showVer   = flag.Bool("version", false, "Show version")
-	)
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		exitIf(runConfig(os.Args[2:]), "Error")
+		return
+	}
+
+	cfg, err := loadConfig(".")
+	exitIf(err, "Error loading config")
+
+	flags := defineFlags(flag.CommandLine)
+	cfg.bindFlags(flag.CommandLine)
 	flag.Parse()
+	cfg.markFlags(flag.CommandLine)
+	warnIgnoredHooks(os.Stderr, cfg, flags)
 
-	if *showVer {
+	if flags.showVer {
 		fmt.Printf("treeforge v%s\n", version)
 		return
 	}
 
+	norm, err := treeforge.ParseNormalization(cfg.Normalize)
+	exitIf(err, "Error")
+	opts := treeforge.Options{RootName: cfg.RootName, Normalize: norm, Strict: cfg.Strict}
+	opts.Include = includeFiles(filepath.Dir(flags.inputFile))
+	opts.Vars = templateVars(cfg.Vars)
+
+	// Ctrl-C or SIGTERM stops an apply after the entry in progress; a
+	// second signal kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if flags.prune && !flags.watch {
+		exitIf(errors.New("--prune only works with --watch"), "Error")
+	}
+	if flags.watch {
+		exitIf(runWatch(ctx, flags, cfg, opts), "Error")
+		return
+	}
+	if flags.stream {
+		exitIf(runStream(ctx, flags, cfg, opts), "Error")
+		return
+	}
+
 	// Read input
-	lines, err := processInput(*inputFile, *verbose)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
-		os.Exit(1)
+	in, err := readInput(flags.inputFile, cfg.Verbose)
+	exitIf(err, "Error reading input")
+	lines := in.lines
+	if in.include != nil {
+		opts.Include = in.include
 	}
 
 	if len(lines) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: empty input\n")
-		os.Exit(1)
+		exitIf(errors.New("empty input"), "Error")
 	}
 
-	if *verbose {
+	if cfg.Verbose {
 		fmt.Printf("Read %d lines\n", len(lines))
 	}
 
 	// Parse tree structure
-	entries, err := ParseTree(lines)
+	tree, err := treeforge.ParseLines(lines, opts)
+	exitIf(err, "Error parsing tree")
+
+	if cfg.Verbose {
+		fmt.Printf("Parsed %d entries\n", len(tree.Entries))
+	}
+
+	exitIf(fillContent(tree, in, cfg), "Error reading content")
+
+	exitIf(run(ctx, tree, flags, cfg, checkpointKey(lines, cfg, flags.noIgnore)), "Error")
+}
+
+// emitScript prints a script that creates the plan when run.
+func emitScript(plan *treeforge.Plan, format string) error {
+	f, err := treeforge.ParseScriptFormat(format)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing tree: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+	return treeforge.WriteScript(os.Stdout, plan, f)
+}
+
+// loadIgnore collects the patterns that filter entries out of the tree:
+// ignore files already present under base, then the exclude patterns.
+// .git is always left alone.
+func loadIgnore(base string, exclude []string, noIgnore bool) (*treeforge.Ignore, error) {
+	ig := &treeforge.Ignore{}
+	if !noIgnore {
+		var err error
+		if ig, err = treeforge.LoadIgnoreDir(base); err != nil {
+			return nil, err
+		}
+	}
+	if err := ig.Add("", "exclude", exclude); err != nil {
+		return nil, err
+	}
+	if err := ig.Add("", "built-in", []string{".git/"}); err != nil {
+		return nil, err
+	}
+	return ig, nil
+}
+
+// prepareTree checks the tree and applies exclusions and, with
+// --interactive, the user's review. It reports false if the user
+// cancelled the review.
+func prepareTree(tree *treeforge.Tree, flags *cliFlags, cfg *Config) (bool, error) {
+	if err := checkDuplicates(os.Stderr, tree); err != nil {
+		return false, err
+	}
+
+	base := filepath.Join(cfg.Parent, tree.Root)
+	ig, err := loadIgnore(base, cfg.Exclude, flags.noIgnore)
+	if err != nil {
+		return false, err
+	}
+	tree.Exclude(ig)
+
+	if flags.interactive {
+		if ok, err := reviewTree(tree, base); !ok || err != nil {
+			if err == nil {
+				fmt.Println("Cancelled; nothing was created.")
+			}
+			return false, err
+		}
+		// Renames can collide; confirming stands in for --apply.
+		if err := checkDuplicates(os.Stderr, tree); err != nil {
+			return false, err
+		}
+		flags.apply = true
+	}
+
+	if err := checkPortable(os.Stderr, tree, cfg.Portable); err != nil {
+		return false, err
+	}
+
+	if cfg.Git {
+		tree.AddGitkeep()
+	}
+	return true, nil
+}
+
+// run writes the parsed tree to its destination: an archive, a script,
+// a dry-run listing, or the filesystem. key identifies the run's
+// checkpoint.
+func run(ctx context.Context, tree *treeforge.Tree, flags *cliFlags, cfg *Config, key string) error {
+	if flags.resume && (!(flags.apply || flags.interactive) || flags.rollback) {
+		return errors.New("--resume needs --apply and cannot be combined with --rollback")
+	}
+	if err := checkConflictFlags(flags, cfg); err != nil {
+		return err
+	}
+	if ok, err := prepareTree(tree, flags, cfg); !ok || err != nil {
+		return err
+	}
+
+	if flags.archive != "" {
+		return writeArchive(tree, flags.archive, cfg.Verbose)
 	}
 
-	if *verbose {
-		fmt.Printf("Parsed %d entries\n", len(entries))
+	plan := treeforge.NewPlan(tree, cfg.Parent)
+	plan.Force = cfg.Force
+	plan.Jobs = cfg.Jobs
+	if err := setConflicts(plan, cfg, newConflictPrompt(ctx, flags.apply)); err != nil {
+		return err
 	}
 
-	// Determine root name and create base path
-	root := determineRootName(*rootName, lines[0])
-	basePath := filepath.Join(*parent, root)
+	if flags.emit != "" {
+		return emitScript(plan, flags.emit)
+	}
 
 	// Dry-run or apply
-	if !*apply {
-		printDryRun(basePath, entries)
-		return
+	if !flags.apply {
+		if err := printDryRun(plan, enabledHooks(cfg, flags)); err != nil {
+			return err
+		}
+		if cfg.Git {
+			fmt.Printf("Git: would initialize a repository in %s%s\n", plan.Base, gitCommitNote(cfg))
+		}
+		return nil
 	}
 
-	// Apply mode: create files and directories
-	if *verbose {
-		fmt.Printf("Creating structure in: %s\n", basePath)
+	return applyPlan(ctx, plan, flags, cfg, key)
+}
+
+// applyPlan creates the plan on disk with a checkpoint, then runs the
+// hooks and sets up git.
+func applyPlan(ctx context.Context, plan *treeforge.Plan, flags *cliFlags, cfg *Config, key string) error {
+	if cfg.Verbose {
+		fmt.Printf("Creating structure in: %s\n", plan.Base)
 	}
 
-	if err := applyEntries(basePath, entries, *force, *verbose); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	report, err := applyCheckpointed(ctx, plan, flags, cfg.Verbose, key)
+	if err != nil {
+		return err
+	}
+	if err := afterApply(ctx, enabledHooks(cfg, flags), plan, report.Results, countWritten(report.Results), cfg.Verbose); err != nil {
+		return err
+	}
+	if cfg.Git {
+		return setupGit(plan.Base, report.Results, cfg.GitCommit, cfg.GitMessage, cfg.Verbose)
 	}
+	return nil
 }
 
-func readFromFile(path string, verbose bool) ([]string, error) {
-	if verbose {
-		fmt.Printf("Reading from file: %s\n", path)
+func gitCommitNote(cfg *Config) string {
+	if !cfg.GitCommit {
+		return ""
+	}
+	return fmt.Sprintf(" and commit %q", cfg.GitMessage)
+}
+
+// runConfig implements the "treeforge config" subcommand.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "show" {
+		return fmt.Errorf("usage: treeforge config show [flags]")
 	}
 
-	file, err := os.Open(path)
+	cfg, err := loadConfig(".")
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer file.Close()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	cfg.bindFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
 	}
+	cfg.markFlags(fs)
 
-	return lines, scanner.Err()
+	printConfig(os.Stdout, cfg)
+	return nil
 }
 
-func readFromStdin(verbose bool) ([]string, error) {
+// openInput opens the input file, or stdin when path is empty.
+func openInput(path string, verbose bool) (io.ReadCloser, error) {
+	if path != "" {
+		if verbose {
+			fmt.Printf("Reading from file: %s\n", path)
+		}
+		return os.Open(path)
+	}
+
 	if verbose {
 		fmt.Println("Reading from stdin...")
 	}
@@ -213,32 +526,17 @@ func readFromStdin(verbose bool) ([]string, error) {
 		// stdin is from terminal (not piped)
 		fmt.Fprintln(os.Stderr, "Paste your tree structure (press Ctrl+D when done):")
 	}
+	return io.NopCloser(os.Stdin), nil
+}
 
+// readLines reads all of r, with no limit on line length.
+func readLines(r io.Reader) ([]string, error) {
 	var lines []string
-	scanner := bufio.NewScanner(os.Stdin)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, math.MaxInt32)
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
 
 	return lines, scanner.Err()
 }
-
-func countDirs(entries []Entry) int {
-	count := 0
-	for _, e := range entries {
-		if e.Kind == KindDir {
-			count++
-		}
-	}
-	return count
-}
-
-func countFiles(entries []Entry) int {
-	count := 0
-	for _, e := range entries {
-		if e.Kind == KindFile {
-			count++
-		}
-	}
-	return count
-}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/qooh0/treeforge/pkg/treeforge"
+)
+
+// watchInterval is how often --watch checks the input file.
+const watchInterval = 500 * time.Millisecond
+
+// watcher re-applies the input file each time it changes. It only ever
+// creates the entries that were added since the last cycle, and prunes
+// only what it created itself and nobody has touched since.
+type watcher struct {
+	flags *cliFlags
+	cfg   *Config
+	opts  treeforge.Options
+
+	last    []byte               // input as of the last cycle
+	targets []string             // targets of the last plan, in order
+	created map[string]createdAs // what this session created
+	prompt  *conflictPrompt      // shared by every cycle, nil without --apply
+}
+
+// createdAs records an entry created by the watcher, so pruning can tell
+// whether it was changed afterwards.
+type createdAs struct {
+	kind    treeforge.Kind
+	content []byte
+}
+
+func runWatch(ctx context.Context, flags *cliFlags, cfg *Config, opts treeforge.Options) error {
+	switch {
+	case flags.inputFile == "":
+		return errors.New("--watch needs an input file (-i)")
+	case flags.archive != "" || flags.emit != "" || flags.interactive || flags.resume || flags.rollback || cfg.Git:
+		return errors.New("--watch cannot be combined with --output-archive, --emit, --interactive, --resume, --rollback or --git")
+	}
+	if _, _, ok := packedInput(flags.inputFile); ok {
+		return errors.New("--watch needs a plain tree file, not a git repository or archive")
+	}
+
+	w := &watcher{flags: flags, cfg: cfg, opts: opts, created: map[string]createdAs{}, prompt: newConflictPrompt(ctx, flags.apply)}
+	defer w.prompt.close()
+	fmt.Printf("Watching %s (Ctrl-C to stop)\n", flags.inputFile)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopped watching.")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll runs a cycle if the input file's content changed. Errors, such as
+// a half-saved file that does not parse, are reported and the watch
+// carries on.
+func (w *watcher) poll(ctx context.Context) error {
+	data, err := os.ReadFile(w.flags.inputFile)
+	if err != nil || (w.last != nil && bytes.Equal(data, w.last)) {
+		return err
+	}
+	w.last = data
+	return w.cycle(ctx, data)
+}
+
+// cycle parses one version of the input and brings the target in line
+// with it.
+func (w *watcher) cycle(ctx context.Context, data []byte) error {
+	lines, err := readLines(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	tree, err := treeforge.ParseLines(lines, w.opts)
+	if err != nil {
+		return err
+	}
+	if err := fillContent(tree, nil, w.cfg); err != nil {
+		return err
+	}
+	if _, err := prepareTree(tree, w.flags, w.cfg); err != nil {
+		return err
+	}
+
+	plan := treeforge.NewPlan(tree, w.cfg.Parent)
+	plan.Force = w.cfg.Force
+	plan.Jobs = w.cfg.Jobs
+	if err := setConflicts(plan, w.cfg, w.prompt); err != nil {
+		return err
+	}
+
+	added, removed := w.changes(plan)
+	fmt.Printf("\n[%s] %s: %d added, %d removed\n", time.Now().Format("15:04:05"), w.flags.inputFile, len(added), len(removed))
+	targets := targetsOf(plan.Steps)
+	plan.Steps = added
+	for _, step := range added {
+		fmt.Printf("  + %s\n", step.Target)
+	}
+
+	if w.flags.apply {
+		if err := w.apply(ctx, plan, removed); err != nil {
+			return err
+		}
+	} else {
+		for _, target := range removed {
+			fmt.Printf("  - %s\n", target)
+		}
+	}
+	// Only a cycle that went through counts; after a failure the next
+	// change retries everything that is still missing.
+	w.targets = targets
+	return nil
+}
+
+// apply creates the added steps and, with --prune, removes what dropped
+// out of the tree.
+func (w *watcher) apply(ctx context.Context, plan *treeforge.Plan, removed []string) error {
+	plan.OnResult = func(res treeforge.Result) {
+		if res.Status == treeforge.StatusCreated || res.Status == treeforge.StatusFilled {
+			w.created[res.Step.Target] = createdAs{kind: res.Step.Entry.Kind, content: res.Step.Entry.Content}
+		}
+	}
+	if len(plan.Steps) > 0 {
+		report, err := applyEntries(ctx, plan, w.cfg.Verbose, false)
+		if err != nil {
+			return err
+		}
+		if err := afterApply(ctx, enabledHooks(w.cfg, w.flags), plan, report.Results, countWritten(report.Results), w.cfg.Verbose); err != nil {
+			return err
+		}
+	}
+
+	for _, target := range removed {
+		fmt.Printf("  - %s%s\n", target, w.prune(target))
+	}
+	return nil
+}
+
+// changes compares plan with the previous cycle, returning the steps
+// that are new and the targets that are gone, deepest first.
+func (w *watcher) changes(plan *treeforge.Plan) (added []treeforge.Step, removed []string) {
+	before, now := map[string]bool{}, map[string]bool{}
+	for _, target := range w.targets {
+		before[target] = true
+	}
+	for _, step := range plan.Steps {
+		now[step.Target] = true
+		if !before[step.Target] {
+			added = append(added, step)
+		}
+	}
+	for i := len(w.targets) - 1; i >= 0; i-- {
+		if !now[w.targets[i]] {
+			removed = append(removed, w.targets[i])
+		}
+	}
+	return added, removed
+}
+
+// prune removes target if --prune is set and this session created it
+// unchanged, and returns a note saying what happened.
+func (w *watcher) prune(target string) string {
+	c, ok := w.created[target]
+	switch {
+	case !w.flags.prune:
+		return ""
+	case !ok:
+		return " (kept: not created by this watch)"
+	case c.kind == treeforge.KindFile && !sameContent(target, c.content):
+		return " (kept: modified)"
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		if c.kind == treeforge.KindDir {
+			return " (kept: not empty)"
+		}
+		return fmt.Sprintf(" (kept: %v)", err)
+	}
+	delete(w.created, target)
+	return " (pruned)"
+}
+
+func sameContent(path string, want []byte) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.Equal(data, want)
+}
+
+func targetsOf(steps []treeforge.Step) []string {
+	targets := make([]string, len(steps))
+	for i, step := range steps {
+		targets[i] = step.Target
+	}
+	return targets
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWatcherCycle(t *testing.T) {
+	parent := t.TempDir()
+	input := filepath.Join(t.TempDir(), "layout.txt")
+	cfg := defaultConfig()
+	cfg.Parent = parent
+	w := &watcher{
+		flags:   &cliFlags{inputFile: input, apply: true, prune: true, noIgnore: true},
+		cfg:     cfg,
+		created: map[string]createdAs{},
+	}
+	path := func(p string) string { return filepath.Join(parent, "app", p) }
+	save := func(layout string) {
+		t.Helper()
+		if err := os.WriteFile(input, []byte(layout), 0644); err != nil {
+			t.Fatalf("Failed to write layout: %v", err)
+		}
+		if err := w.poll(context.Background()); err != nil {
+			t.Fatalf("poll() unexpected error: %v", err)
+		}
+	}
+
+	// With -v each created entry is listed as it is created.
+	w.cfg.Verbose = true
+	out := captureStdout(t, func() { save("app/\n├─ old/\n│  └─ a.go\n├─ edited.go\n└─ main.go\n") })
+	if !strings.Contains(out, "[FILE] "+path("old/a.go")) {
+		t.Errorf("verbose cycle output = %q, want the created files listed", out)
+	}
+	w.cfg.Verbose = false
+	if _, err := os.Stat(path("old/a.go")); err != nil {
+		t.Fatalf("first cycle did not create old/a.go: %v", err)
+	}
+	if err := os.WriteFile(path("edited.go"), []byte("work"), 0644); err != nil {
+		t.Fatalf("Failed to edit: %v", err)
+	}
+
+	// Drop old/ and edited.go, add cmd/.
+	save("app/\n├─ cmd/\n│  └─ run.go\n└─ main.go\n")
+	if _, err := os.Stat(path("cmd/run.go")); err != nil {
+		t.Errorf("second cycle did not create cmd/run.go: %v", err)
+	}
+	if _, err := os.Stat(path("old")); !os.IsNotExist(err) {
+		t.Errorf("old/ was not pruned")
+	}
+	if _, err := os.Stat(path("edited.go")); err != nil {
+		t.Errorf("edited.go was pruned although it had been modified")
+	}
+
+	// An unchanged file does not start a cycle; a broken one is reported.
+	if err := w.poll(context.Background()); err != nil {
+		t.Errorf("poll() of an unchanged file: %v", err)
+	}
+	if err := os.WriteFile(input, nil, 0644); err != nil {
+		t.Fatalf("Failed to write layout: %v", err)
+	}
+	if err := w.poll(context.Background()); err == nil {
+		t.Errorf("poll() of an empty layout expected error")
+	}
+}
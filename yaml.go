@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A minimal YAML reader covering what treeforge config files need:
+// block mappings, block sequences, flow sequences ([a, b]) and
+// plain or quoted scalars. Scalars are returned as strings; callers
+// decide how to interpret them.
+
+type yamlLine struct {
+	indent int
+	text   string
+	num    int
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYAML(data []byte) (map[string]any, error) {
+	lines, err := splitYAMLLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	if lines[0].indent != 0 || isSeqItem(lines[0].text) {
+		return nil, fmt.Errorf("line %d: top level must be a mapping", lines[0].num)
+	}
+	m, err := p.parseMap(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return m, nil
+}
+
+func splitYAMLLines(s string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(s, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimSpace(stripYAMLComment(trimmed))
+		if text == "" || text == "---" {
+			continue
+		}
+		lines = append(lines, yamlLine{indent: len(raw) - len(trimmed), text: text, num: i + 1})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment removes a trailing "# comment" that is not inside quotes.
+func stripYAMLComment(s string) string {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].text) {
+		return p.parseSeq(indent)
+	}
+	return p.parseMap(indent)
+}
+
+func (p *yamlParser) parseMap(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent || isSeqItem(line.text) {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		key, value, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		if m[key], err = p.parseValue(indent, value, line.num); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// parseValue returns the value for a "key: value" line, descending into a
+// nested block when the inline value is empty.
+func (p *yamlParser) parseValue(indent int, value string, num int) (any, error) {
+	if value != "" {
+		return parseYAMLScalar(value, num)
+	}
+	if p.pos >= len(p.lines) {
+		return "", nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent {
+		return p.parseBlock(next.indent)
+	}
+	// A sequence may sit at the same indentation as its key.
+	if next.indent == indent && isSeqItem(next.text) {
+		return p.parseSeq(indent)
+	}
+	return "", nil
+}
+
+func (p *yamlParser) parseSeq(indent int) ([]any, error) {
+	items := []any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isSeqItem(line.text)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		item, err := p.parseSeqItem(line)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (p *yamlParser) parseSeqItem(line yamlLine) (any, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+	if rest == "" {
+		p.pos++
+		if p.pos < len(p.lines) && p.lines[p.pos].indent > line.indent {
+			return p.parseBlock(p.lines[p.pos].indent)
+		}
+		return "", nil
+	}
+
+	if _, _, err := splitYAMLKey(yamlLine{text: rest, num: line.num}); err == nil && !isFlowOrQuoted(rest) {
+		// "- key: value" starts a mapping whose keys are aligned with "key".
+		p.lines[p.pos] = yamlLine{indent: line.indent + len(line.text) - len(rest), text: rest, num: line.num}
+		return p.parseMap(p.lines[p.pos].indent)
+	}
+
+	p.pos++
+	return parseYAMLScalar(rest, line.num)
+}
+
+func isFlowOrQuoted(s string) bool {
+	return strings.HasPrefix(s, "[") || strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'")
+}
+
+func splitYAMLKey(line yamlLine) (key, value string, err error) {
+	idx := strings.Index(line.text, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(line.text, ":") {
+			return "", "", fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		idx = len(line.text) - 1
+	}
+
+	key = strings.TrimSpace(line.text[:idx])
+	if key == "" {
+		return "", "", fmt.Errorf("line %d: empty key", line.num)
+	}
+	if isFlowOrQuoted(key) {
+		unquoted, err := unquoteYAML(key, line.num)
+		if err != nil {
+			return "", "", err
+		}
+		key = unquoted
+	}
+	return key, strings.TrimSpace(line.text[idx+1:]), nil
+}
+
+func parseYAMLScalar(s string, num int) (any, error) {
+	if strings.HasPrefix(s, "[") {
+		return parseYAMLFlowSeq(s, num)
+	}
+	if strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'") {
+		return unquoteYAML(s, num)
+	}
+	return s, nil
+}
+
+func parseYAMLFlowSeq(s string, num int) ([]any, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("line %d: unterminated flow sequence", num)
+	}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	items := []any{}
+	if inner == "" {
+		return items, nil
+	}
+	for _, part := range splitFlowItems(inner) {
+		v, err := parseYAMLScalar(strings.TrimSpace(part), num)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// splitFlowItems splits on commas that are not inside quotes.
+func splitFlowItems(s string) []string {
+	var parts []string
+	var quote rune
+	start := 0
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unquoteYAML(s string, num int) (string, error) {
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	unquoted, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("line %d: invalid quoted string %s", num, s)
+	}
+	return unquoted, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]any
+		hasError bool
+	}{
+		{
+			name:     "empty document",
+			input:    "# only a comment\n",
+			expected: map[string]any{},
+		},
+		{
+			name:  "scalars and comments",
+			input: "parent: ~/projects  # where to build\nforce: true\nroot-name: \"my app\"\nquote: 'it''s'\n",
+			expected: map[string]any{
+				"parent":    "~/projects",
+				"force":     "true",
+				"root-name": "my app",
+				"quote":     "it's",
+			},
+		},
+		{
+			name:  "block and flow sequences",
+			input: "exclude:\n  - node_modules\n  - \"*.log\"\nextra: [a, 'b, c', \"d\"]\nempty: []\n",
+			expected: map[string]any{
+				"exclude": []any{"node_modules", "*.log"},
+				"extra":   []any{"a", "b, c", "d"},
+				"empty":   []any{},
+			},
+		},
+		{
+			name:  "sequence at key indentation",
+			input: "exclude:\n- a\n- b\nforce: false\n",
+			expected: map[string]any{
+				"exclude": []any{"a", "b"},
+				"force":   "false",
+			},
+		},
+		{
+			name:  "nested mappings and sequences of mappings",
+			input: "hooks:\n  after:\n    - run: go mod init\n      timeout: 10s\n    - run: git init\n",
+			expected: map[string]any{
+				"hooks": map[string]any{
+					"after": []any{
+						map[string]any{"run": "go mod init", "timeout": "10s"},
+						map[string]any{"run": "git init"},
+					},
+				},
+			},
+		},
+		{
+			name:     "tab indentation",
+			input:    "a:\n\tb: c\n",
+			hasError: true,
+		},
+		{
+			name:     "unexpected indentation",
+			input:    "a: b\n  c: d\n",
+			hasError: true,
+		},
+		{
+			name:     "duplicate key",
+			input:    "a: b\na: c\n",
+			hasError: true,
+		},
+		{
+			name:     "missing colon",
+			input:    "just text\n",
+			hasError: true,
+		},
+		{
+			name:     "top level sequence",
+			input:    "- a\n",
+			hasError: true,
+		},
+		{
+			name:     "unterminated flow sequence",
+			input:    "a: [b, c\n",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseYAML([]byte(tt.input))
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("parseYAML() expected error, got %v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseYAML() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseYAML() = %#v, want %#v", result, tt.expected)
+			}
+		})
+	}
+}